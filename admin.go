@@ -0,0 +1,197 @@
+package iqfeed
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AdminClient provides the client for the IQFeed admin port which reports the health of the feed and the clients connected
+// to IQConnect. IQConnect sends S,STATS once a second on this port, S,CLIENTSTATS is sent once ClientStatsOn is called.
+type AdminClient struct {
+	Stats       chan *SystemStats    // Receives every S,STATS message.
+	ClientStats chan *ClientStatsMsg // Receives every S,CLIENTSTATS message.
+	System      chan *SystemMessage  // Receives all other system messages (ex: S,REGISTER CLIENT APP COMPLETED).
+	ParseErrors chan *ParseError     // Receives malformed messages that were dropped, buffered and dropped when full so it may be ignored.
+	TimeZone    string
+	TimeLoc     *time.Location
+	Conn        net.Conn
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+// Start connects to the admin port (defaults to localhost:9300) and starts reading, cancelling ctx has the same effect as
+// calling Close.
+func (a *AdminClient) Start(ctx context.Context, connectString string) (*AdminClient, error) {
+	var err error
+	a.TimeLoc, err = loadTimeLoc(&a.TimeZone, a.TimeLoc)
+	if err != nil {
+		return nil, err
+	}
+	if connectString == "" {
+		connectString = "localhost:9300"
+	}
+	a.Conn, err = net.Dial("tcp", connectString)
+	if err != nil {
+		return nil, &DialError{Addr: connectString, Err: err}
+	}
+	a.Stats = make(chan *SystemStats)
+	a.ClientStats = make(chan *ClientStatsMsg)
+	a.System = make(chan *SystemMessage)
+	a.ParseErrors = make(chan *ParseError, 100)
+	a.ctx, a.cancel = context.WithCancel(ctx)
+	a.wg.Add(2)
+	go a.read()
+	go func() {
+		defer a.wg.Done()
+		<-a.ctx.Done()
+		a.Conn.Close()
+	}()
+	return a, nil
+}
+
+// Close closes the connection to the admin port and returns once all goroutines have exited and channels are closed.
+func (a *AdminClient) Close() error {
+	if a.cancel == nil {
+		return nil
+	}
+	a.cancel()
+	a.wg.Wait()
+	return nil
+}
+
+// Read function does as expected and reads data from the network stream until the client is closed.
+func (a *AdminClient) read() {
+	defer a.wg.Done()
+	defer func() {
+		close(a.Stats)
+		close(a.ClientStats)
+		close(a.System)
+		close(a.ParseErrors)
+	}()
+	r := bufio.NewReader(a.Conn)
+	for {
+		line, isPrefix, err := r.ReadLine()
+		if err != nil {
+			if a.ctx.Err() == nil {
+				log.Printf("Admin connection closed: %s", err)
+				a.cancel()
+			}
+			return
+		}
+		if isPrefix {
+			log.Println("buffer size to small")
+			continue
+		}
+		a.processReceiver(line)
+	}
+}
+
+// ProcessReceiver interprets the system messages received on the admin port.
+func (a *AdminClient) processReceiver(d []byte) {
+	if len(d) < 3 || d[0] != 'S' {
+		log.Printf("Unknown admin message: %s", d)
+		return
+	}
+	items := strings.Split(strings.TrimSuffix(string(d[2:]), ","), ",")
+	switch items[0] {
+	case "STATS":
+		s := &SystemStats{}
+		if err := s.UnMarshall(items[1:], a.TimeLoc); err != nil {
+			reportParseError(a.ParseErrors, withRaw(err, d))
+			return
+		}
+		select {
+		case a.Stats <- s:
+		case <-a.ctx.Done():
+		}
+	case "CLIENTSTATS":
+		c := &ClientStatsMsg{}
+		if err := c.UnMarshall(items[1:], a.TimeLoc); err != nil {
+			reportParseError(a.ParseErrors, withRaw(err, d))
+			return
+		}
+		select {
+		case a.ClientStats <- c:
+		case <-a.ctx.Done():
+		}
+	default:
+		s := &SystemMessage{}
+		if err := s.UnMarshall(d[2:], a.TimeLoc); err != nil {
+			reportParseError(a.ParseErrors, err)
+			return
+		}
+		select {
+		case a.System <- s:
+		case <-a.ctx.Done():
+		}
+	}
+}
+
+// Write sends the raw command to the admin port.
+func (a *AdminClient) Write(data string) {
+	a.Conn.Write([]byte(data))
+}
+
+// onOff formats b as On or Off.
+func onOff(b bool) string {
+	if b {
+		return "On"
+	}
+	return "Off"
+}
+
+// RegisterClientApp registers the application with IQConnect using the product ID issued by DTN and the application version.
+func (a *AdminClient) RegisterClientApp(productID, version string) {
+	a.Write("S,REGISTER CLIENT APP," + productID + "," + version + "\r\n")
+}
+
+// RemoveClientApp removes the registration of the application from IQConnect.
+func (a *AdminClient) RemoveClientApp(productID, version string) {
+	a.Write("S,REMOVE CLIENT APP," + productID + "," + version + "\r\n")
+}
+
+// SetLoginID sets the login ID IQConnect uses to connect to the servers.
+func (a *AdminClient) SetLoginID(loginID string) {
+	a.Write("S,SET LOGINID," + loginID + "\r\n")
+}
+
+// SetPassword sets the password IQConnect uses to connect to the servers.
+func (a *AdminClient) SetPassword(password string) {
+	a.Write("S,SET PASSWORD," + password + "\r\n")
+}
+
+// SetSaveLoginInfo sets whether IQConnect saves the login ID and password.
+func (a *AdminClient) SetSaveLoginInfo(save bool) {
+	a.Write("S,SET SAVE LOGIN INFO," + onOff(save) + "\r\n")
+}
+
+// SetAutoconnect sets whether IQConnect connects to the servers without showing the login dialog.
+func (a *AdminClient) SetAutoconnect(auto bool) {
+	a.Write("S,SET AUTOCONNECT," + onOff(auto) + "\r\n")
+}
+
+// ClientStatsOn turns on the periodic S,CLIENTSTATS messages for every connected client.
+func (a *AdminClient) ClientStatsOn() {
+	a.Write("S,CLIENTSTATS ON\r\n")
+}
+
+// ClientStatsOff turns off the periodic S,CLIENTSTATS messages.
+func (a *AdminClient) ClientStatsOff() {
+	a.Write("S,CLIENTSTATS OFF\r\n")
+}
+
+// Connect Tells IQFeed to initiate a connection to the servers.
+func (a *AdminClient) Connect() {
+	a.Write("S,CONNECT\r\n")
+}
+
+// Disconnect Tells IQFeed to disconnect from the servers.
+func (a *AdminClient) Disconnect() {
+	a.Write("S,DISCONNECT\r\n")
+}
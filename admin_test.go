@@ -0,0 +1,105 @@
+package iqfeed
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The admin lines follow the examples in http://www.iqfeed.net/dev/api/docs/AdminSystemMessages.cfm.
+const (
+	statsLine       = "S,STATS,66.112.148.225,60002,1300,2,3,0,1,4,Mar 05 9:30AM,Mar 05 10:15AM,Connected,6.2.0.25,123456,1234.56,1.23,0.45,567.89,0.98,0.12,"
+	clientStatsLine = "S,CLIENTSTATS,1,7,Charts,20240305 093012,25,2,120.50,4096.25,0.00,"
+	// badClientStatsLine is sent before clientStatsLine, it must be reported on ParseErrors rather than ClientStats.
+	badClientStatsLine = "S,CLIENTSTATS,1,8,Charts,03/05/2024 09:30:12,25,2,120.50,4096.25,0.00,"
+)
+
+func TestAdminClient(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte(statsLine + "\r\n"))
+		r := bufio.NewReader(conn)
+		for {
+			cmd, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch strings.TrimRight(cmd, "\r\n") {
+			case "S,CLIENTSTATS ON":
+				conn.Write([]byte(badClientStatsLine + "\r\n" + clientStatsLine + "\r\n"))
+			case "S,REGISTER CLIENT APP,APP_ID,1.0":
+				conn.Write([]byte("S,REGISTER CLIENT APP COMPLETED,\r\n"))
+			}
+		}
+	}()
+
+	a := &AdminClient{TimeZone: "America/New_York"}
+	if _, err := a.Start(context.Background(), ln.Addr().String()); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	defer a.Close()
+	year := statsYear(a.TimeLoc)
+
+	select {
+	case s := <-a.Stats:
+		want := SystemStats{ServerIP: "66.112.148.225", ServerPort: 60002, MaxSymbols: 1300, NumberOfSymbols: 2, ClientsConnected: 3,
+			Reconnections: 1, AttemptedReconnections: 4, Status: "Connected", IQFeedVersion: "6.2.0.25", LoginID: "123456",
+			TotalKBsRecv: 1234.56, KBsPerSecRecv: 1.23, AvgKBsPerSecRecv: 0.45, TotalKBsSent: 567.89, KBsPerSecSent: 0.98, AvgKBsPerSecSent: 0.12,
+			StartTime: time.Date(year, time.March, 5, 9, 30, 0, 0, a.TimeLoc), MarketTime: time.Date(year, time.March, 5, 10, 15, 0, 0, a.TimeLoc)}
+		if !s.StartTime.Equal(want.StartTime) || !s.MarketTime.Equal(want.MarketTime) {
+			t.Errorf("stats times %s, %s, want %s, %s", s.StartTime, s.MarketTime, want.StartTime, want.MarketTime)
+		}
+		s.StartTime, s.MarketTime = want.StartTime, want.MarketTime
+		if *s != want {
+			t.Errorf("stats = %+v, want %+v", *s, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for S,STATS")
+	}
+
+	a.ClientStatsOn()
+	select {
+	case c := <-a.ClientStats:
+		want := ClientStatsMsg{Type: 1, ClientID: 7, ClientName: "Charts", StartTime: time.Date(2024, time.March, 5, 9, 30, 12, 0, a.TimeLoc),
+			SymbolsWatched: 25, RegionalSymbolsWatched: 2, KBReceived: 120.5, KBSent: 4096.25}
+		if !c.StartTime.Equal(want.StartTime) {
+			t.Errorf("client start time %s, want %s", c.StartTime, want.StartTime)
+		}
+		c.StartTime = want.StartTime
+		if *c != want {
+			t.Errorf("client stats = %+v, want %+v", *c, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for S,CLIENTSTATS")
+	}
+	select {
+	case perr := <-a.ParseErrors:
+		if perr.Message != "ClientStatsMsg" || perr.Field != "StartTime" || perr.Raw != badClientStatsLine {
+			t.Errorf("parse error = %+v", perr)
+		}
+	default:
+		t.Error("the malformed S,CLIENTSTATS was not reported")
+	}
+
+	a.RegisterClientApp("APP_ID", "1.0")
+	select {
+	case s := <-a.System:
+		if s.Raw != "REGISTER CLIENT APP COMPLETED," {
+			t.Errorf("system message = %+v", s)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the other system messages")
+	}
+}
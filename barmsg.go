@@ -0,0 +1,48 @@
+package iqfeed
+
+import (
+	"strings"
+	"time"
+)
+
+// BarType distinguishes the interval bar messages streamed by the derivative port.
+type BarType string
+
+const (
+	BarHistory    BarType = "BH" // A historical bar sent as backfill when the watch starts.
+	BarComplete   BarType = "BC" // A bar whose interval has completed, it will not change again.
+	BarInProgress BarType = "BU" // An update to the bar currently being built.
+)
+
+// BarMsg is a single interval bar streamed by the derivative port, see: http://www.iqfeed.net/dev/api/docs/Derivatives_StreamingIntervalBars_TCPIP.cfm.
+type BarMsg struct {
+	RequestID   string    // The request ID the watch was started with
+	Type        BarType   // History, complete or in-progress bar
+	Symbol      string    // The Symbol ID to match with watch request
+	TimeStamp   time.Time // Time label of the bar (CCYY-MM-DD HH:MM:SS)
+	Open        float64   // First trade price of the bar
+	High        float64   // Highest trade price of the bar
+	Low         float64   // Lowest trade price of the bar
+	Last        float64   // Last trade price of the bar
+	CumVolume   int       // Cumulative volume for the day at the time of the bar
+	IntervalVol int       // Volume traded during the bar
+	NumTrades   int       // Number of trades during the bar
+}
+
+// UnMarshall sends the data into the usable struct for consumption by the application, items start at the bar type.
+// A *ParseError is returned when the line is too short or a field is invalid.
+func (b *BarMsg) UnMarshall(requestID string, items []string, loc *time.Location) error {
+	p := newFieldParser("BarMsg", strings.Join(items, ","), items, loc)
+	b.RequestID = requestID
+	b.Type = BarType(p.str(0, "Type"))
+	b.Symbol = p.str(1, "Symbol")
+	b.TimeStamp = p.dateTime(2, "TimeStamp")
+	b.Open = p.float(3, "Open")
+	b.High = p.float(4, "High")
+	b.Low = p.float(5, "Low")
+	b.Last = p.float(6, "Last")
+	b.CumVolume = p.int(7, "CumVolume")
+	b.IntervalVol = p.int(8, "IntervalVol")
+	b.NumTrades = p.int(9, "NumTrades")
+	return p.error()
+}
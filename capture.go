@@ -0,0 +1,204 @@
+package iqfeed
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Compression selects how capture files are compressed. Only gzip is built in as the package has no dependencies outside
+// the standard library, other formats such as zstd are supported through CaptureWriter.Compressor.
+type Compression int
+
+const (
+	CompressNone Compression = iota
+	CompressGzip
+)
+
+// CaptureWriter records the raw feed to disk. Every record is written as [receive time in unix nanoseconds][space][raw line]\r\n
+// through a buffered writer that stays open between records. Files are rotated by size and / or trading day, rotated files
+// are named after the base Path with the time they were opened (ex: feed.20240102-093000.000.log.gz).
+type CaptureWriter struct {
+	Path          string                                    // The base file name, rotated files are created next to it.
+	MaxSize       int64                                     // Rotate once this many bytes (before compression) have been written, 0 to disable.
+	RotateDaily   bool                                      // Rotate when the trading day in Loc changes.
+	Loc           *time.Location                            // The location used for trading days and file names, defaults to time.Local.
+	Compression   Compression                               // Ignored when Compressor is set.
+	Compressor    func(w io.Writer) (io.WriteCloser, error) // Custom compression (ex: zstd), Ext is appended to file names.
+	Ext           string                                    // The extension added for a custom Compressor (ex: .zst).
+	FlushInterval time.Duration                             // Flush buffered records at least this often, defaults to one second.
+	mu            sync.Mutex
+	stop          chan struct{} // Stops the flusher started by the first WriteLine, see Close.
+	done          chan struct{} // Closed once the flusher has returned.
+	f             *os.File
+	zw            io.WriteCloser // The compressor writing to f, nil when uncompressed.
+	buf           *bufio.Writer
+	size          int64
+	day           string
+	lastFlush     time.Time
+}
+
+// WriteLine writes a single raw line received at recv, rotating the file first when required.
+func (w *CaptureWriter) WriteLine(recv time.Time, line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.rotateIfNeeded(recv); err != nil {
+		return err
+	}
+	if w.stop == nil {
+		w.stop, w.done = make(chan struct{}), make(chan struct{})
+		go w.flusher(w.flushInterval(), w.stop, w.done)
+	}
+	var ts [20]byte
+	n, _ := w.buf.Write(strconv.AppendInt(ts[:0], recv.UnixNano(), 10))
+	w.buf.WriteByte(' ')
+	w.buf.Write(line)
+	_, err := w.buf.WriteString("\r\n")
+	w.size += int64(n + len(line) + 3)
+	if err != nil {
+		return err
+	}
+	if recv.Sub(w.lastFlush) >= w.flushInterval() {
+		w.lastFlush = recv
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *CaptureWriter) flushInterval() time.Duration {
+	if w.FlushInterval <= 0 {
+		return time.Second
+	}
+	return w.FlushInterval
+}
+
+// Flusher flushes the buffered records every interval until stop is closed, so records reach the file on a quiet feed
+// where no further WriteLine would flush them.
+func (w *CaptureWriter) flusher(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := w.Flush(); err != nil {
+				log.Printf("Could not flush capture file: %s", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// flush pushes the buffered records through the compressor, if any, so they reach the file.
+func (w *CaptureWriter) flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if f, ok := w.zw.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Flush writes any buffered records to the file.
+func (w *CaptureWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf == nil {
+		return nil
+	}
+	return w.flush()
+}
+
+// Close stops the background flushes, flushes the buffered records, finishes the compressed stream and closes the file.
+func (w *CaptureWriter) Close() error {
+	w.mu.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	err := w.closeFile()
+	w.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	return err
+}
+
+func (w *CaptureWriter) closeFile() error {
+	if w.f == nil {
+		return nil
+	}
+	err := w.buf.Flush()
+	if w.zw != nil {
+		if zerr := w.zw.Close(); err == nil {
+			err = zerr
+		}
+	}
+	if ferr := w.f.Close(); err == nil {
+		err = ferr
+	}
+	w.f, w.zw, w.buf = nil, nil, nil
+	return err
+}
+
+func (w *CaptureWriter) rotateIfNeeded(now time.Time) error {
+	loc := w.Loc
+	if loc == nil {
+		loc = time.Local
+	}
+	day := now.In(loc).Format("20060102")
+	if w.f != nil && !(w.MaxSize > 0 && w.size >= w.MaxSize) && !(w.RotateDaily && day != w.day) {
+		return nil
+	}
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	return w.open(now.In(loc), day)
+}
+
+// Open creates the next capture file, when no rotation is configured the base Path is appended to instead.
+func (w *CaptureWriter) open(now time.Time, day string) error {
+	name := w.Path
+	rotating := w.MaxSize > 0 || w.RotateDaily
+	if rotating {
+		ext := filepath.Ext(w.Path)
+		name = fmt.Sprintf("%s.%s%s", strings.TrimSuffix(w.Path, ext), now.Format("20060102-150405.000"), ext)
+	}
+	switch {
+	case w.Compressor != nil:
+		name += w.Ext
+	case w.Compression == CompressGzip:
+		name += ".gz"
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	var out io.Writer = f
+	var zw io.WriteCloser
+	switch {
+	case w.Compressor != nil:
+		zw, err = w.Compressor(f)
+	case w.Compression == CompressGzip:
+		zw = gzip.NewWriter(f)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if zw != nil {
+		out = zw
+	}
+	w.f, w.zw, w.buf = f, zw, bufio.NewWriterSize(out, 64*1024)
+	w.size, w.day, w.lastFlush = 0, day, now
+	return nil
+}
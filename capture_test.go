@@ -0,0 +1,95 @@
+package iqfeed
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readCapture returns the content of a capture file, decompressing it when required.
+func readCapture(t *testing.T, name string) string {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(name, ".gz") {
+		if r, err = gzip.NewReader(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestCaptureWriter(t *testing.T) {
+	recv := time.Date(2024, time.March, 5, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		w     *CaptureWriter
+		times []time.Time
+		files []string
+	}{
+		{"plain", &CaptureWriter{}, []time.Time{recv, recv.Add(time.Millisecond)}, []string{"feed.log"}},
+		{"gzip", &CaptureWriter{Compression: CompressGzip}, []time.Time{recv, recv.Add(time.Millisecond)}, []string{"feed.log.gz"}},
+		{"size", &CaptureWriter{MaxSize: 10}, []time.Time{recv, recv.Add(time.Millisecond), recv.Add(2 * time.Millisecond)},
+			[]string{"feed.20240305-093000.000.log", "feed.20240305-093000.001.log", "feed.20240305-093000.002.log"}},
+		{"daily", &CaptureWriter{RotateDaily: true, Compression: CompressGzip}, []time.Time{recv, recv.Add(time.Hour), recv.Add(24 * time.Hour)},
+			[]string{"feed.20240305-093000.000.log.gz", "feed.20240306-093000.000.log.gz"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			w := tt.w
+			w.Path, w.Loc = filepath.Join(dir, "feed.log"), time.UTC
+			var want strings.Builder
+			for i, recv := range tt.times {
+				line := "Q,AAPL," + strconv.Itoa(i)
+				if err := w.WriteLine(recv, []byte(line)); err != nil {
+					t.Fatal(err)
+				}
+				want.WriteString(strconv.FormatInt(recv.UnixNano(), 10) + " " + line + "\r\n")
+			}
+			// Close must write the records that are still buffered.
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			var got strings.Builder
+			for _, name := range tt.files {
+				got.WriteString(readCapture(t, filepath.Join(dir, name)))
+			}
+			if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != len(tt.files) {
+				t.Errorf("files = %v, want %v", files, tt.files)
+			}
+			if got.String() != want.String() {
+				t.Errorf("captured %q, want %q", got.String(), want.String())
+			}
+		})
+	}
+}
+
+func TestCaptureWriterFlushInterval(t *testing.T) {
+	w := &CaptureWriter{Path: filepath.Join(t.TempDir(), "feed.log"), FlushInterval: 10 * time.Millisecond}
+	defer w.Close()
+	if err := w.WriteLine(time.Now(), []byte("T,20240305 09:30:00")); err != nil {
+		t.Fatal(err)
+	}
+	// No further record arrives, the buffered one must still reach the file.
+	deadline := time.Now().Add(5 * time.Second)
+	for readCapture(t, w.Path) == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("the record was not flushed on a quiet feed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,36 @@
+package iqfeed
+
+import (
+	"strings"
+	"time"
+)
+
+// ClientStatsMsg is sent on the admin port for every client connected to IQConnect once client stats are turned on.
+// See: http://www.iqfeed.net/dev/api/docs/AdminSystemMessages.cfm.
+type ClientStatsMsg struct {
+	Type                   int       // 0 = Admin, 1 = Level 1, 2 = Level 2, 3 = Lookup, 4 = Derivative
+	ClientID               int       // The ID of the client connection
+	ClientName             string    // The name set with S,SET CLIENT NAME
+	StartTime              time.Time // The time the client connected (CCYYMMDD HHMMSS)
+	SymbolsWatched         int       // The # of symbols watched by the client
+	RegionalSymbolsWatched int       // The # of regional symbols watched by the client
+	KBReceived             float32   // KBs received by the client
+	KBSent                 float32   // KBs sent to the client
+	KBQueued               float32   // KBs queued to be sent to the client
+}
+
+// UnMarshall sends the S,CLIENTSTATS fields (starting after the CLIENTSTATS name) into the usable struct for consumption by the application,
+// a *ParseError is returned when a field is missing or invalid.
+func (c *ClientStatsMsg) UnMarshall(items []string, loc *time.Location) error {
+	p := newFieldParser("ClientStatsMsg", strings.Join(items, ","), items, loc)
+	c.Type = p.int(0, "Type")
+	c.ClientID = p.int(1, "ClientID")
+	c.ClientName = p.str(2, "ClientName")
+	c.StartTime = p.time(3, "StartTime", "20060102 150405")
+	c.SymbolsWatched = p.int(4, "SymbolsWatched")
+	c.RegionalSymbolsWatched = p.int(5, "RegionalSymbolsWatched")
+	c.KBReceived = float32(p.float(6, "KBReceived"))
+	c.KBSent = float32(p.float(7, "KBSent"))
+	c.KBQueued = float32(p.float(8, "KBQueued"))
+	return p.error()
+}
@@ -0,0 +1,96 @@
+package iqfeed
+
+// ContentFlags is the decoded Message Contents field of an update, the trade and quote events that caused the message.
+// A message holds at most one trade flag and may hold none when the fields that changed are not trade or quote related.
+type ContentFlags uint16
+
+// The Message Contents codes, see: http://www.iqfeed.net/dev/api/docs/Level1UpdateSummaryMessage.cfm.
+const (
+	ContentLastQualifiedTrade ContentFlags = 1 << iota // C - A last qualified trade.
+	ContentExtendedTrade                               // E - An extended trade, a Form T trade.
+	ContentOtherTrade                                  // O - Any trade not accounted for by C or E.
+	ContentBid                                         // b - A bid update.
+	ContentAsk                                         // a - An ask update.
+	ContentOpen                                        // o - An open.
+	ContentHigh                                        // h - A new high.
+	ContentLow                                         // l - A new low.
+	ContentClose                                       // c - A close.
+	ContentSettle                                      // s - A settlement.
+	ContentVolume                                      // v - A volume update.
+)
+
+// contentCodes holds the code of every flag in flag order.
+const contentCodes = "CEObaohlcsv"
+
+// ParseContentFlags decodes a Message Contents value (ex: Cbav), unknown codes are ignored.
+func ParseContentFlags(s string) ContentFlags {
+	var f ContentFlags
+	for i := 0; i < len(s); i++ {
+		for j := 0; j < len(contentCodes); j++ {
+			if s[i] == contentCodes[j] {
+				f |= 1 << j
+				break
+			}
+		}
+	}
+	return f
+}
+
+// Has reports whether every flag in flags is set.
+func (f ContentFlags) Has(flags ContentFlags) bool {
+	return f&flags == flags
+}
+
+// IsTrade reports whether the message was caused by a trade of any kind.
+func (f ContentFlags) IsTrade() bool {
+	return f&(ContentLastQualifiedTrade|ContentExtendedTrade|ContentOtherTrade) != 0
+}
+
+// IsQuote reports whether the bid or the ask changed.
+func (f ContentFlags) IsQuote() bool {
+	return f&(ContentBid|ContentAsk) != 0
+}
+
+// IsLastQualifiedTrade reports whether the message was caused by a last qualified trade (C).
+func (f ContentFlags) IsLastQualifiedTrade() bool { return f.Has(ContentLastQualifiedTrade) }
+
+// IsExtendedTrade reports whether the message was caused by an extended trade (E).
+func (f ContentFlags) IsExtendedTrade() bool { return f.Has(ContentExtendedTrade) }
+
+// IsOtherTrade reports whether the message was caused by a trade that is neither last qualified nor extended (O).
+func (f ContentFlags) IsOtherTrade() bool { return f.Has(ContentOtherTrade) }
+
+// IsBidUpdate reports whether the bid changed (b).
+func (f ContentFlags) IsBidUpdate() bool { return f.Has(ContentBid) }
+
+// IsAskUpdate reports whether the ask changed (a).
+func (f ContentFlags) IsAskUpdate() bool { return f.Has(ContentAsk) }
+
+// IsOpen reports whether the message carries the open (o).
+func (f ContentFlags) IsOpen() bool { return f.Has(ContentOpen) }
+
+// IsNewHigh reports whether the message carries a new high (h).
+func (f ContentFlags) IsNewHigh() bool { return f.Has(ContentHigh) }
+
+// IsNewLow reports whether the message carries a new low (l).
+func (f ContentFlags) IsNewLow() bool { return f.Has(ContentLow) }
+
+// IsClose reports whether the message carries the close (c).
+func (f ContentFlags) IsClose() bool { return f.Has(ContentClose) }
+
+// IsSettle reports whether the message carries a settlement (s).
+func (f ContentFlags) IsSettle() bool { return f.Has(ContentSettle) }
+
+// IsVolumeUpdate reports whether the volume changed (v).
+func (f ContentFlags) IsVolumeUpdate() bool { return f.Has(ContentVolume) }
+
+// String returns the codes of the flags as IQFeed sends them (ex: Cbav).
+func (f ContentFlags) String() string {
+	var b []byte
+	for j := 0; j < len(contentCodes); j++ {
+		if f&(1<<j) != 0 {
+			b = append(b, contentCodes[j])
+		}
+	}
+	return string(b)
+}
@@ -35,9 +35,54 @@ func GetTimeInHMSmicro(d string, loc *time.Location) time.Time {
 	return t
 }
 
-// GetDateMMDDCCYY returns a time object after parsing the MM/DD/CCYY layout in iqfeed.
+// GetDateMMDDCCYY returns a time object after parsing the MM/DD/CCYY layout in iqfeed (ex: 03/01/2024). Two digit years
+// (ex: 03/01/24) are still accepted as this function parsed the MM/DD/YY layout before IQFeed's four digit years were
+// supported, they are read as 1969-2068.
 func GetDateMMDDCCYY(d string, loc *time.Location) time.Time {
-	t, _ := time.ParseInLocation("01/02/06", d, loc)
+	t, err := time.ParseInLocation(layoutMMDDCCYY, d, loc)
+	if err != nil {
+		t, _ = time.ParseInLocation("01/02/06", d, loc)
+	}
 
 	return t
 }
+
+// CombineDateTime returns the time of day from tod on the date of date, zero values of tod are returned untouched.
+func combineDateTime(date, tod time.Time) time.Time {
+	if tod.IsZero() || date.IsZero() {
+		return tod
+	}
+	y, m, d := date.Date()
+	return time.Date(y, m, d, tod.Hour(), tod.Minute(), tod.Second(), tod.Nanosecond(), tod.Location())
+}
+
+// isTimeOnly reports whether t was parsed from a time of day without a date.
+func isTimeOnly(t time.Time) bool {
+	return !t.IsZero() && t.Year() == 0
+}
+
+// attachFeedDate places the time of day tod on the trading date of the feed time ref (the latest T message). A time of
+// day more than an hour after ref is taken to be from the previous day, so a trade stamped 23:59:59 that is received
+// after the feed clock passed midnight keeps its date. Values that already carry a date are returned untouched.
+func attachFeedDate(ref, tod time.Time) time.Time {
+	if !isTimeOnly(tod) || ref.IsZero() {
+		return tod
+	}
+	t := combineDateTime(ref, tod)
+	if t.Sub(ref) > time.Hour {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t
+}
+
+// attachMsgDate places the time of day tod on date, the date a message sent for it, falling back to the feed time ref
+// when the message had no date.
+func attachMsgDate(date, ref, tod time.Time) time.Time {
+	if !isTimeOnly(tod) {
+		return tod
+	}
+	if date.IsZero() || isTimeOnly(date) {
+		return attachFeedDate(ref, tod)
+	}
+	return combineDateTime(date, tod)
+}
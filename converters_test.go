@@ -0,0 +1,34 @@
+package iqfeed
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConverters(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	dateTime := func(v string) time.Time {
+		return newFieldParser("", v, []string{v}, loc).dateTime(0, "")
+	}
+	tests := []struct {
+		name string
+		got  time.Time
+		want time.Time
+	}{
+		{"MMDDCCYY", GetDateMMDDCCYY("03/01/2024", loc), time.Date(2024, time.March, 1, 0, 0, 0, 0, loc)},
+		{"MMDDCCYY two digit year", GetDateMMDDCCYY("03/01/24", loc), time.Date(2024, time.March, 1, 0, 0, 0, 0, loc)},
+		{"MMDDCCYY invalid", GetDateMMDDCCYY("13/01/2024", loc), time.Time{}},
+		{"MMDDCCYY empty", GetDateMMDDCCYY("", loc), time.Time{}},
+		{"CCYYMMDD date", dateTime("2024-03-01"), time.Date(2024, time.March, 1, 0, 0, 0, 0, loc)},
+		{"CCYYMMDD micro", dateTime("2024-03-01 09:30:00.123456"), time.Date(2024, time.March, 1, 9, 30, 0, 123456000, loc)},
+		{"HMS", GetTimeInHMS("09:30:15", loc), time.Date(0, time.January, 1, 9, 30, 15, 0, loc)},
+		{"HMS micro", GetTimeInHMSmicro("09:30:15.250", loc), time.Date(0, time.January, 1, 9, 30, 15, 250e6, loc)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.got.Equal(tt.want) {
+				t.Errorf("got %s, want %s", tt.got, tt.want)
+			}
+		})
+	}
+}
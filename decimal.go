@@ -0,0 +1,161 @@
+package iqfeed
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxPriceScale is the largest scale a Price can hold without overflowing the int64 for realistic prices.
+const maxPriceScale = 12
+
+var errInvalidPrice = errors.New("invalid price")
+
+// Price is a fixed-point price, Value scaled by 10^Scale (ex: 95.0200 is {950200 4}). Prices are parsed digit for digit
+// from the feed so they round-trip exactly, unlike the float64 fields which are kept for convenience.
+type Price struct {
+	Value int64
+	Scale uint8
+}
+
+// ParsePrice parses a decimal price as sent by IQFeed (ex: 95.0200, -0.05, 1200), the scale is the number of digits sent
+// after the decimal point.
+func ParsePrice(s string) (Price, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || len(frac) > maxPriceScale {
+		return Price{}, errInvalidPrice
+	}
+	var v int64
+	for _, part := range [2]string{whole, frac} {
+		for i := 0; i < len(part); i++ {
+			c := part[i]
+			if c < '0' || c > '9' || v > (math.MaxInt64-9)/10 {
+				return Price{}, errInvalidPrice
+			}
+			v = v*10 + int64(c-'0')
+		}
+	}
+	if neg {
+		v = -v
+	}
+	return Price{Value: v, Scale: uint8(len(frac))}, nil
+}
+
+// Rescale returns the price with the given scale, it returns false when that would drop digits or overflow.
+func (p Price) Rescale(scale uint8) (Price, bool) {
+	if scale > maxPriceScale {
+		return p, false
+	}
+	v := p.Value
+	for s := p.Scale; s < scale; s++ {
+		if v > math.MaxInt64/10 || v < math.MinInt64/10 {
+			return p, false
+		}
+		v *= 10
+	}
+	for s := p.Scale; s > scale; s-- {
+		if v%10 != 0 {
+			return p, false
+		}
+		v /= 10
+	}
+	return Price{Value: v, Scale: scale}, true
+}
+
+// align returns both prices at the larger of their scales, it returns false when that would overflow.
+func align(a, b Price) (Price, Price, bool) {
+	ok := true
+	if a.Scale < b.Scale {
+		a, ok = a.Rescale(b.Scale)
+	} else if b.Scale < a.Scale {
+		b, ok = b.Rescale(a.Scale)
+	}
+	return a, b, ok
+}
+
+// Cmp compares the prices regardless of their scales, it returns -1, 0 or 1.
+func (p Price) Cmp(o Price) int {
+	a, b, ok := align(p, o)
+	if !ok {
+		// One of the prices is too large to rescale, compare them exactly instead.
+		return p.rat().Cmp(o.rat())
+	}
+	switch {
+	case a.Value < b.Value:
+		return -1
+	case a.Value > b.Value:
+		return 1
+	}
+	return 0
+}
+
+// rat returns the price as an exact fraction.
+func (p Price) rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(p.Value), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(p.Scale)), nil))
+}
+
+// Add returns p + o at the larger of their scales, it returns false when the result overflows.
+func (p Price) Add(o Price) (Price, bool) {
+	a, b, ok := align(p, o)
+	v := a.Value + b.Value
+	if !ok || b.Value > 0 && v < a.Value || b.Value < 0 && v > a.Value {
+		return Price{}, false
+	}
+	return Price{Value: v, Scale: a.Scale}, true
+}
+
+// Sub returns p - o at the larger of their scales, it returns false when the result overflows.
+func (p Price) Sub(o Price) (Price, bool) {
+	a, b, ok := align(p, o)
+	v := a.Value - b.Value
+	if !ok || b.Value > 0 && v > a.Value || b.Value < 0 && v < a.Value {
+		return Price{}, false
+	}
+	return Price{Value: v, Scale: a.Scale}, true
+}
+
+// Float64 returns the nearest float64 to the price.
+func (p Price) Float64() float64 {
+	f, _ := strconv.ParseFloat(p.String(), 64)
+	return f
+}
+
+// String formats the price with Scale digits after the decimal point, as IQFeed sent it.
+func (p Price) String() string {
+	v := p.Value
+	neg := v < 0
+	if neg {
+		v = -v
+	}
+	digits := strconv.FormatInt(v, 10)
+	if p.Scale > 0 {
+		if pad := int(p.Scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		digits = digits[:len(digits)-int(p.Scale)] + "." + digits[len(digits)-int(p.Scale):]
+	}
+	if neg {
+		return "-" + digits
+	}
+	return digits
+}
+
+// toPrice parses v as a Price at no less than precision digits, the precision of the message (ex: DecPrecision).
+func (p *fieldParser) toPrice(field, v string, precision int) Price {
+	d, err := ParsePrice(v)
+	if err != nil {
+		p.fail(field, v, err)
+		return Price{}
+	}
+	if precision > int(d.Scale) {
+		if r, ok := d.Rescale(uint8(precision)); ok {
+			d = r
+		}
+	}
+	return d
+}
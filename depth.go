@@ -0,0 +1,64 @@
+package iqfeed
+
+import (
+	"strings"
+	"time"
+)
+
+// DepthMsg is a Level 2 summary (Z) or update (2) message for a single market maker or price level of a symbol.
+// For equities MMID identifies a market maker, for futures it identifies a price level (ex: MD01 is the best level).
+// See: http://www.iqfeed.net/dev/api/docs/Level2MessageFormat.cfm.
+type DepthMsg struct {
+	Type           string    // Z for a summary message or 2 for an update message.
+	Symbol         string    // The Symbol ID to match with watch request
+	MMID           string    // Market maker or price level ID
+	Bid            float64   // The market maker's bid price
+	Ask            float64   // The market maker's ask price
+	BidSize        int       // The market maker's bid size
+	AskSize        int       // The market maker's ask size
+	BidTime        time.Time // Time of the last bid including the date of the message
+	Date           time.Time // Date of the message (CCYY-MM-DD)
+	ConditionCode  string    // Quote condition code, see Quote Condition Codes.
+	AskTime        time.Time // Time of the last ask including the date of the message
+	BidInfoValid   bool      // False when the bid side should be removed from the book
+	AskInfoValid   bool      // False when the ask side should be removed from the book
+	EndOfMsgGroup  bool      // True on the last message of a group of updates for the symbol
+	MarketMakerTag string    // The market maker name when it has been looked up, see L2Client.MarketMakerName.
+}
+
+// UnMarshall sends the data into the usable struct for consumption by the application, a *ParseError is returned when
+// the line is too short or a field is invalid.
+func (m *DepthMsg) UnMarshall(typ string, d []byte, loc *time.Location) error {
+	p := newFieldParser("DepthMsg", string(d), strings.Split(string(d), ","), loc)
+	m.Type = typ
+	m.Symbol = p.str(0, "Symbol")
+	m.MMID = p.str(1, "MMID")
+	m.Bid = p.float(2, "Bid")
+	m.Ask = p.float(3, "Ask")
+	m.BidSize = p.int(4, "BidSize")
+	m.AskSize = p.int(5, "AskSize")
+	m.Date = p.dateTime(7, "Date")
+	m.BidTime = combineDateTime(m.Date, p.time(6, "BidTime", layoutHMS))
+	m.ConditionCode = p.str(8, "ConditionCode")
+	m.AskTime = combineDateTime(m.Date, p.time(9, "AskTime", layoutHMS))
+	m.BidInfoValid = p.str(10, "BidInfoValid") == "T"
+	m.AskInfoValid = p.str(11, "AskInfoValid") == "T"
+	m.EndOfMsgGroup = len(p.items) > 12 && p.items[12] == "T"
+	return p.error()
+}
+
+// MarketMakerMsg is the response to a market maker name lookup (M,[MMID],[Description]).
+type MarketMakerMsg struct {
+	MMID string // Market maker ID
+	Name string // Market maker name or description
+}
+
+// UnMarshall sends the data into the usable struct for consumption by the application, a *ParseError is returned when
+// the name is missing.
+func (m *MarketMakerMsg) UnMarshall(d []byte) error {
+	raw := string(d)
+	p := newFieldParser("MarketMakerMsg", raw, strings.SplitN(raw, ",", 2), nil)
+	m.MMID = p.str(0, "MMID")
+	m.Name = strings.TrimSuffix(p.str(1, "Name"), ",")
+	return p.error()
+}
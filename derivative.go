@@ -0,0 +1,227 @@
+package iqfeed
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BarWatch describes a streaming interval bar watch (BW) on the derivative port.
+type BarWatch struct {
+	Symbol         string
+	Interval       int          // The size of each bar in units of Type.
+	Type           IntervalType // Defaults to IntervalSeconds.
+	Begin          time.Time    // Backfill history from this time, zero to use MaxDays / MaxDatapoints instead.
+	MaxDays        int          // The maximum number of days of history to backfill.
+	MaxDatapoints  int          // The maximum number of history bars to backfill.
+	BeginFilter    string       // Only build bars after this time of day in HHmmSS format.
+	EndFilter      string       // Only build bars before this time of day in HHmmSS format.
+	UpdateInterval int          // Seconds between in-progress (BU) updates, 0 to send one on every trade.
+}
+
+func (w *BarWatch) command(id string, loc *time.Location) string {
+	typ := w.Type
+	if typ == "" {
+		typ = IntervalSeconds
+	}
+	return fmt.Sprintf("BW,%s,%d,%s,%s,%s,%s,%s,%s,%s,,%s\r\n", w.Symbol, w.Interval, optTime(w.Begin, loc), optInt(w.MaxDays),
+		optInt(w.MaxDatapoints), w.BeginFilter, w.EndFilter, id, typ, optInt(w.UpdateInterval))
+}
+
+// DerivClient provides the client for the IQFeed derivative port which streams interval bars built by IQConnect.
+type DerivClient struct {
+	Bars        chan *BarMsg // Receives history, complete and in-progress bars for every watch.
+	System      chan *SystemMessage
+	Errors      chan *ErrorMsg
+	Time        chan *TimeMsg
+	ParseErrors chan *ParseError // Receives malformed messages that were dropped, buffered and dropped when full so it may be ignored.
+	TimeZone    string
+	TimeLoc     *time.Location
+	Conn        net.Conn
+	mu          sync.Mutex
+	watches     map[string]*BarWatch // Active watches keyed by request ID.
+	nextID      int
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+// Start connects to the derivative port (defaults to localhost:9400) and starts reading bars, cancelling ctx has the same
+// effect as calling Close.
+func (d *DerivClient) Start(ctx context.Context, connectString string) (*DerivClient, error) {
+	var err error
+	d.TimeLoc, err = loadTimeLoc(&d.TimeZone, d.TimeLoc)
+	if err != nil {
+		return nil, err
+	}
+	if connectString == "" {
+		connectString = "localhost:9400"
+	}
+	d.Conn, err = net.Dial("tcp", connectString)
+	if err != nil {
+		return nil, &DialError{Addr: connectString, Err: err}
+	}
+	d.watches = make(map[string]*BarWatch)
+	d.Bars = make(chan *BarMsg)
+	d.System = make(chan *SystemMessage)
+	d.Errors = make(chan *ErrorMsg)
+	d.Time = make(chan *TimeMsg)
+	d.ParseErrors = make(chan *ParseError, 100)
+	d.ctx, d.cancel = context.WithCancel(ctx)
+	d.wg.Add(2)
+	go d.read()
+	go func() {
+		defer d.wg.Done()
+		<-d.ctx.Done()
+		d.Conn.Close()
+	}()
+	return d, nil
+}
+
+// Close closes the connection to the derivative port and returns once all goroutines have exited and channels are closed.
+func (d *DerivClient) Close() error {
+	if d.cancel == nil {
+		return nil
+	}
+	d.cancel()
+	d.wg.Wait()
+	return nil
+}
+
+// Read function does as expected and reads data from the network stream until the client is closed.
+func (d *DerivClient) read() {
+	defer d.wg.Done()
+	defer func() {
+		close(d.Bars)
+		close(d.System)
+		close(d.Errors)
+		close(d.Time)
+		close(d.ParseErrors)
+	}()
+	r := bufio.NewReader(d.Conn)
+	for {
+		line, isPrefix, err := r.ReadLine()
+		if err != nil {
+			if d.ctx.Err() == nil {
+				log.Printf("Derivative connection closed: %s", err)
+				d.cancel()
+			}
+			return
+		}
+		if isPrefix {
+			log.Println("buffer size to small")
+			continue
+		}
+		d.processReceiver(string(line))
+	}
+}
+
+// ProcessReceiver interprets the data received on the derivative port, lines for a watch are prefixed with its request ID.
+func (d *DerivClient) processReceiver(line string) {
+	items := strings.Split(strings.TrimSuffix(line, ","), ",")
+	d.mu.Lock()
+	_, ok := d.watches[items[0]]
+	abandoned := !ok && d.isWatchID(items[0])
+	d.mu.Unlock()
+	if abandoned {
+		// Lines still in flight for a watch removed by UnwatchBars.
+		return
+	}
+	var id string
+	if ok && len(items) > 1 {
+		id, items = items[0], items[1:]
+	}
+	switch items[0] {
+	case string(BarHistory), string(BarComplete), string(BarInProgress):
+		b := &BarMsg{}
+		if err := b.UnMarshall(id, items, d.TimeLoc); err != nil {
+			reportParseError(d.ParseErrors, err)
+			return
+		}
+		select {
+		case d.Bars <- b:
+		case <-d.ctx.Done():
+		}
+	case "T":
+		t := &TimeMsg{}
+		if err := t.UnMarshall([]byte(strings.Join(items[1:], ",")), d.TimeLoc); err != nil {
+			reportParseError(d.ParseErrors, err)
+			return
+		}
+		select {
+		case d.Time <- t:
+		case <-d.ctx.Done():
+		}
+	case "S":
+		s := &SystemMessage{}
+		if err := s.UnMarshall([]byte(strings.Join(items[1:], ",")), d.TimeLoc); err != nil {
+			reportParseError(d.ParseErrors, err)
+			return
+		}
+		select {
+		case d.System <- s:
+		case <-d.ctx.Done():
+		}
+	case "n", "E":
+		e := &ErrorMsg{}
+		if items[0] == "n" {
+			e.UnMarshall(true, []byte(strings.Join(items[1:], ",")), 404)
+		} else {
+			e.UnMarshall(false, []byte(strings.Join(items[1:], ",")), 500)
+		}
+		e.RequestID = id
+		select {
+		case d.Errors <- e:
+		case <-d.ctx.Done():
+		}
+	default:
+		log.Printf("Unknown derivative message type: %q", items[0])
+	}
+}
+
+// isWatchID reports whether s is a request ID issued by WatchBars (ex: B1), d.mu must be held.
+func (d *DerivClient) isWatchID(s string) bool {
+	if len(s) < 2 || s[0] != 'B' {
+		return false
+	}
+	n, err := strconv.Atoi(s[1:])
+	return err == nil && n > 0 && n <= d.nextID
+}
+
+// Write sends the raw command to the derivative port.
+func (d *DerivClient) Write(data string) {
+	d.Conn.Write([]byte(data))
+}
+
+// WatchBars starts streaming interval bars for the watch and returns the request ID that tags its bars.
+func (d *DerivClient) WatchBars(w *BarWatch) string {
+	d.mu.Lock()
+	d.nextID++
+	id := "B" + strconv.Itoa(d.nextID)
+	d.watches[id] = w
+	d.mu.Unlock()
+	d.Write(w.command(id, d.TimeLoc))
+	return id
+}
+
+// UnwatchBars stops the bar watch that was started with the request ID.
+func (d *DerivClient) UnwatchBars(id string) {
+	d.mu.Lock()
+	w, ok := d.watches[id]
+	delete(d.watches, id)
+	d.mu.Unlock()
+	if ok {
+		d.Write("BR," + w.Symbol + "," + id + "\r\n")
+	}
+}
+
+// SetProtocol Changes the current connection's protocol (ex: 5.2).
+func (d *DerivClient) SetProtocol(protocol string) {
+	d.Write("S,SET PROTOCOL," + protocol + "\r\n")
+}
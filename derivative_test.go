@@ -0,0 +1,129 @@
+package iqfeed
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBarWatchCommand(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	tests := []struct {
+		name string
+		w    BarWatch
+		want string
+	}{
+		{"seconds", BarWatch{Symbol: "AAPL", Interval: 60, MaxDatapoints: 10}, "BW,AAPL,60,,,10,,,B1,s,,\r\n"},
+		{"begin", BarWatch{Symbol: "AAPL", Interval: 300, Begin: time.Date(2024, time.March, 5, 9, 30, 0, 0, loc), BeginFilter: "093000",
+			EndFilter: "160000", UpdateInterval: 5}, "BW,AAPL,300,20240305 093000,,,093000,160000,B1,s,,5\r\n"},
+		{"volume", BarWatch{Symbol: "@ES#", Interval: 1000, Type: IntervalVolume, MaxDays: 2}, "BW,@ES#,1000,,2,,,,B1,v,,\r\n"},
+		{"ticks", BarWatch{Symbol: "AAPL", Interval: 50, Type: IntervalTicks}, "BW,AAPL,50,,,,,,B1,t,,\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.w.command("B1", loc); got != tt.want {
+				t.Errorf("command = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDerivClient(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	cmds := make(chan string, 4)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			cmd, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmds <- strings.TrimRight(cmd, "\r\n")
+			switch {
+			case strings.HasPrefix(cmd, "BW,NOPE,"):
+				conn.Write([]byte("B2,E,Invalid symbol.,\r\n"))
+			case strings.HasPrefix(cmd, "BR,"):
+				// A bar that was already in flight when the watch was removed, followed by the time.
+				conn.Write([]byte("B1,BU,AAPL,2024-03-05 09:33:00,170.30,170.36,170.30,170.36,13200,200,2,\r\n" +
+					"T,20240305 09:33:01\r\n"))
+			case strings.HasPrefix(cmd, "BW,"):
+				conn.Write([]byte("B1,BH,AAPL,2024-03-05 09:31:00,170.10,170.50,170.00,170.25,12545,1200,14,\r\n" +
+					"B1,BC,AAPL,2024-03-05 09:32:00,170.25,170.40,170.20,170.30,13000,455,6,\r\n" +
+					"B1,BU,AAPL,2024-03-05 09:33:00,170.30,170.35,170.30,170.31,13100,100,1,\r\n"))
+			}
+		}
+	}()
+
+	d := &DerivClient{TimeZone: "America/New_York"}
+	if _, err := d.Start(context.Background(), ln.Addr().String()); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	defer d.Close()
+	id := d.WatchBars(&BarWatch{Symbol: "AAPL", Interval: 60, MaxDatapoints: 1})
+	if id != "B1" {
+		t.Errorf("request ID = %q", id)
+	}
+	want := []BarMsg{
+		{RequestID: "B1", Type: BarHistory, Symbol: "AAPL", TimeStamp: time.Date(2024, time.March, 5, 9, 31, 0, 0, d.TimeLoc),
+			Open: 170.10, High: 170.50, Low: 170.00, Last: 170.25, CumVolume: 12545, IntervalVol: 1200, NumTrades: 14},
+		{RequestID: "B1", Type: BarComplete, Symbol: "AAPL", TimeStamp: time.Date(2024, time.March, 5, 9, 32, 0, 0, d.TimeLoc),
+			Open: 170.25, High: 170.40, Low: 170.20, Last: 170.30, CumVolume: 13000, IntervalVol: 455, NumTrades: 6},
+		{RequestID: "B1", Type: BarInProgress, Symbol: "AAPL", TimeStamp: time.Date(2024, time.March, 5, 9, 33, 0, 0, d.TimeLoc),
+			Open: 170.30, High: 170.35, Low: 170.30, Last: 170.31, CumVolume: 13100, IntervalVol: 100, NumTrades: 1},
+	}
+	for _, w := range want {
+		select {
+		case b := <-d.Bars:
+			if !b.TimeStamp.Equal(w.TimeStamp) {
+				t.Errorf("%s time = %s, want %s", w.Type, b.TimeStamp, w.TimeStamp)
+			}
+			b.TimeStamp = w.TimeStamp
+			if *b != w {
+				t.Errorf("bar = %+v, want %+v", *b, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the %s bar", w.Type)
+		}
+	}
+	if cmd := <-cmds; cmd != "BW,AAPL,60,,,1,,,B1,s,," {
+		t.Errorf("watch command = %q", cmd)
+	}
+	d.UnwatchBars(id)
+	select {
+	case cmd := <-cmds:
+		if cmd != "BR,AAPL,B1" {
+			t.Errorf("unwatch command = %q", cmd)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for BR")
+	}
+	select {
+	case b := <-d.Bars:
+		t.Errorf("bar received after UnwatchBars: %+v", b)
+	case <-d.Time:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the time message")
+	}
+
+	id = d.WatchBars(&BarWatch{Symbol: "NOPE", Interval: 60})
+	select {
+	case e := <-d.Errors:
+		if e.RequestID != id || e.Message != "Invalid symbol." || e.Code != 500 {
+			t.Errorf("error = %+v, want request %s", e, id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the error")
+	}
+}
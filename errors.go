@@ -1,10 +1,17 @@
 package iqfeed
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
 // ErrorMsg contains error messages reported to the client including symbol not found messages
 type ErrorMsg struct {
-	Symbol  string // Symbol is set on 404 messages to indicate the missing symbol
-	Message string // The error message
-	Code    int    // The http status representation of the error.
+	Symbol    string // Symbol is set on 404 messages to indicate the missing symbol
+	Message   string // The error message
+	Code      int    // The http status representation of the error.
+	RequestID string // The request the error belongs to (ex: the ID returned by DerivClient.WatchBars), empty for other errors.
 }
 
 // UnMarshall sends the data into the usable struct for consumption by the application.
@@ -18,3 +25,119 @@ func (e *ErrorMsg) UnMarshall(notFound bool, d []byte, code int) {
 	e.Code = 500
 	e.Message = string(d)
 }
+
+// errNotConnected is returned when writing to a client that has no connection, such as a replay.
+var errNotConnected = errors.New("iqfeed: not connected")
+
+// DialError is returned when a connection to IQConnect could not be established.
+type DialError struct {
+	Addr string // The address we attempted to dial
+	Err  error  // The underlying network error
+}
+
+func (e *DialError) Error() string {
+	return fmt.Sprintf("iqfeed: could not connect to IQFeed at %s: %s", e.Addr, e.Err)
+}
+
+// Unwrap returns the underlying network error.
+func (e *DialError) Unwrap() error {
+	return e.Err
+}
+
+// TimeZoneError is returned when the configured time zone cannot be loaded.
+type TimeZoneError struct {
+	TimeZone string // The time zone name that failed to load
+	Err      error  // The underlying error from time.LoadLocation
+}
+
+func (e *TimeZoneError) Error() string {
+	return fmt.Sprintf("iqfeed: could not load time zone %q: %s", e.TimeZone, e.Err)
+}
+
+// Unwrap returns the underlying time zone error.
+func (e *TimeZoneError) Unwrap() error {
+	return e.Err
+}
+
+// HandshakeError is returned when the initial requests to IQConnect could not be completed after connecting.
+type HandshakeError struct {
+	Command string // The command that failed during the handshake
+	Err     error  // The underlying error
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("iqfeed: handshake failed on %q: %s", e.Command, e.Err)
+}
+
+// Unwrap returns the underlying handshake error.
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// LookupError is returned when the lookup server responds to a request with an error message (ex: E,Invalid symbol.).
+type LookupError struct {
+	RequestID string
+	Message   string
+}
+
+func (e *LookupError) Error() string {
+	if e.RequestID == "" {
+		return "iqfeed: lookup: " + e.Message
+	}
+	return "iqfeed: lookup request " + e.RequestID + ": " + e.Message
+}
+
+// ReplyError is returned when IQConnect rejects a command with an error message or does not reply to it in time.
+type ReplyError struct {
+	Command string // The command that was sent
+	Message string // The error message from IQConnect or the reason we stopped waiting
+}
+
+func (e *ReplyError) Error() string {
+	return fmt.Sprintf("iqfeed: %q: %s", e.Command, e.Message)
+}
+
+// ProtocolError is returned when IQConnect acknowledges a different protocol than the one requested.
+type ProtocolError struct {
+	Requested string // The protocol we asked for
+	Current   string // The protocol IQConnect reports as current
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("iqfeed: requested protocol %s but IQConnect is using %s", e.Requested, e.Current)
+}
+
+// FieldError is returned by IQC.SelectFields when IQConnect does not offer or did not select some of the update fields.
+type FieldError struct {
+	Fields []UpdField // The fields that are not available
+}
+
+func (e *FieldError) Error() string {
+	names := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		names[i] = f.String()
+	}
+	return "iqfeed: update fields not available: " + strings.Join(names, ", ")
+}
+
+// ParseError is returned when a message received from IQFeed is malformed, the message is dropped and the error is reported
+// instead (see IQC.ParseErrors).
+type ParseError struct {
+	Message string // The message type that failed to parse (ex: FundamentalMsg)
+	Field   string // The field that is missing or invalid
+	Value   string // The invalid value, empty for missing fields
+	Raw     string // The raw line
+	Err     error  // The underlying conversion error
+}
+
+func (e *ParseError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("iqfeed: %s: %s: %s in %q", e.Message, e.Field, e.Err, e.Raw)
+	}
+	return fmt.Sprintf("iqfeed: %s: %s: invalid value %q in %q", e.Message, e.Field, e.Value, e.Raw)
+}
+
+// Unwrap returns the underlying conversion error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
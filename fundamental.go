@@ -62,64 +62,185 @@ type FundamentalMsg struct {
 	StrikePrice        float64   // IEOptions only
 	NAICS              int       // North American Industry Classification System (http://www.census.gov/eos/www/naics/)
 	ExchangeRoot       string    // The root symbol that you can find this symbol listed under at the exchange.
+	OptionPremMult     float64   // Option premium multiplier, protocol 6.0 and newer only.
+	OptionMultDeliv    int       // Option multiple deliverable, protocol 6.0 and newer only.
+	SessionOpenTime    string    // The time the session of a future opens (HH:MM:SS), protocol 6.1 and newer only.
+	SessionCloseTime   string    // The time the session of a future closes (HH:MM:SS), protocol 6.1 and newer only.
+	BaseCurrency       string    // The currency the symbol is priced in (ex: USD), protocol 6.1 and newer only.
+	ContractSize       string    // The size of a futures contract, protocol 6.1 and newer only.
+	ContractMonths     string    // The month codes a future is listed for (ex: HMUZ), protocol 6.1 and newer only.
+	MinTickSize        float64   // The minimum price change, protocol 6.1 and newer only.
+	FirstDeliveryDate  time.Time // The first delivery date of a future (MM/DD/YYYY), protocol 6.1 and newer only.
+	FIGI               string    // The Financial Instrument Global Identifier, protocol 6.2 and newer only.
+	SecuritySubType    string    // The security sub type code, protocol 6.2 and newer only.
+	Present            uint64    // The fields below 64 that were not blank, see Has.
+	presentHigh        uint64    // The fields from 64 that were not blank.
+	// DecimalPrices keeps the exact prices of the price fields when it is set before UnMarshall, see Decimal.
+	DecimalPrices bool
+	// decimals holds the exact prices by their position in fundamentalPrices.
+	decimals   [len(fundamentalPrices)]Price
+	decimalSet uint16 // The positions of decimals that were sent.
 }
 
-// UnMarshall sends the data into the usable struct for consumption by the application.
-func (f *FundamentalMsg) UnMarshall(d []byte, loc *time.Location) {
-	items := strings.Split(string(d), ",")
-	f.Symbol = items[0]                                  // APL,
-	f.ExchaangeID = items[1]                             // 5,
-	f.PE = GetFloatFromStr(items[2])                     // 9.9,
-	f.AvgVolume = GetIntFromStr(items[3])                // 53599000,
-	f.Fifty2WkHigh = GetFloatFromStr(items[4])           // 134.5400,
-	f.Fifty2WkLow = GetFloatFromStr(items[5])            // 92.0000,
-	f.CalYearHigh = GetFloatFromStr(items[6])            // 105.8500,
-	f.CalyearLow = GetFloatFromStr(items[7])             // 92.3900,
-	f.DivYield = GetFloatFromStr(items[8])               // 2.2100,
-	f.DivAmt = GetFloatFromStr(items[9])                 // 0.5200,
-	f.DivRate = GetFloatFromStr(items[10])               // 2.0800,
-	f.PayDate = GetDateMMDDCCYY(items[11], loc)          // 02/11/2016,
-	f.ExDivDate = GetDateMMDDCCYY(items[12], loc)        // 02/04/2016,
-	f.Reserved1 = items[13]                              // ,
-	f.Reserved2 = items[14]                              // ,
-	f.Reserved3 = items[15]                              // ,
-	f.ShortInterest = GetIntFromStr(items[16])           // 63543520,
-	f.Reserved4 = items[17]                              // ,
-	f.CurrentYrEPS = GetFloatFromStr(items[18])          // 9.46,
-	f.NextYrEPS = GetFloatFromStr(items[19])             // ,
-	f.FiveYrGrowthPct = GetFloatFromStr(items[20])       // 0.34,
-	f.FiscalYrEnd = GetIntFromStr(items[21])             // 09,
-	f.Reserved5 = items[22]                              // ,
-	f.CompanyName = items[23]                            // APPLE,
-	f.RootOptionSymbol = strings.Split(items[24], " ")   // AAPL AAPL7,
-	f.PctHeldByInst = GetFloatFromStr(items[25])         // 67.1,
-	f.Beta = GetFloatFromStr(items[26])                  // 1.35,
-	f.Leaps = items[27]                                  // ,
-	f.CurrentAssets = GetFloatFromStr(items[28])         // 89378.0,
-	f.CurrentLiabilities = GetFloatFromStr(items[29])    // 80610.0,
-	f.BalSheetDate = GetDateMMDDCCYY(items[30], loc)     // 12/31/2015,
-	f.LongTermDebt = GetFloatFromStr(items[31])          // 53463.0,
-	f.ComShrOutstanding = GetFloatFromStr(items[32])     // 5544583,
-	f.Reserved6 = items[33]                              // 334220,
-	f.SplitFactor1 = items[34]                           // 0.14 06/09/2014,
-	f.SplitFactor2 = items[35]                           // 0.50 02/28/2005,
-	f.Reserved7 = items[36]                              // ,
-	f.Reserved8 = items[37]                              // 0,
-	f.FormatCode = items[38]                             // 14,
-	f.Precision = GetIntFromStr(items[39])               // 4,
-	f.SIC = GetIntFromStr(items[40])                     // 3571,
-	f.HistVolatility = GetFloatFromStr(items[41])        // 36.98,
-	f.SecurityType = items[42]                           // 1,
-	f.ListedMarket = items[43]                           // 21,
-	f.Fifty2WkHighDate = GetDateMMDDCCYY(items[44], loc) // 04/28/2015,
-	f.Fifty2WkLowDate = GetDateMMDDCCYY(items[45], loc)  // 08/24/2015,
-	f.CalYearHighDate = GetDateMMDDCCYY(items[46], loc)  // 01/05/2016,
-	f.CalYearLowDate = GetDateMMDDCCYY(items[47], loc)   // 01/28/2016,
-	f.YrEndClose = GetFloatFromStr(items[48])            // 105.26,
-	f.MaturityDate = GetDateMMDDCCYY(items[49], loc)     // ,
-	f.CouponRate = GetFloatFromStr(items[50])            // ,
-	f.ExpirationDate = GetDateMMDDCCYY(items[51], loc)   // ,
-	f.StrikePrice = GetFloatFromStr(items[52])           // ,
-	f.NAICS = GetIntFromStr(items[53])                   // 334220,
-	f.ExchangeRoot = items[54]                           // ,
+// fundamentalPrices are the fundamental fields holding prices with their names, they are kept exactly when
+// DecimalPrices is set.
+var fundamentalPrices = [...]struct {
+	field FundamentalField
+	name  string
+}{
+	{FndFifty2WkHigh, "Fifty2WkHigh"}, {FndFifty2WkLow, "Fifty2WkLow"}, {FndCalYearHigh, "CalYearHigh"},
+	{FndCalYearLow, "CalyearLow"}, {FndDivAmt, "DivAmt"}, {FndDivRate, "DivRate"}, {FndCurrentYrEPS, "CurrentYrEPS"},
+	{FndNextYrEPS, "NextYrEPS"}, {FndYrEndClose, "YrEndClose"}, {FndStrikePrice, "StrikePrice"},
+}
+
+// Decimal returns the exact price sent for the field, it is only available when DecimalPrices was set.
+func (f *FundamentalMsg) Decimal(field FundamentalField) (Price, bool) {
+	for i, fp := range fundamentalPrices {
+		if fp.field == field {
+			return f.decimals[i], f.decimalSet&(1<<i) != 0
+		}
+	}
+	return Price{}, false
+}
+
+// unMarshallDecimals keeps the exact prices at no less than the precision of the symbol.
+func (f *FundamentalMsg) unMarshallDecimals(p *fieldParser) {
+	if !f.DecimalPrices {
+		return
+	}
+	for i, fp := range fundamentalPrices {
+		if v := p.str(int(fp.field), fp.name); v != "" {
+			f.decimals[i] = p.toPrice(fp.name, v, f.Precision)
+			f.decimalSet |= 1 << i
+		}
+	}
+}
+
+// Has reports whether the field was sent with a value, telling a blank field apart from a zero value.
+func (f *FundamentalMsg) Has(field FundamentalField) bool {
+	if field >= 64 {
+		return f.presentHigh&(1<<(field-64)) != 0
+	}
+	return f.Present&(1<<field) != 0
+}
+
+// fundamentalLayouts holds the number of fields of the fundamental message by protocol, newest first. Every protocol
+// appends its fields to the layout of the one before it.
+var fundamentalLayouts = []struct {
+	version ProtocolVersion
+	fields  int
+}{
+	{ProtocolVersion{Major: 6, Minor: 2}, int(FndSecuritySubType) + 1},   // FIGI and the security sub type
+	{ProtocolVersion{Major: 6, Minor: 1}, int(FndFirstDeliveryDate) + 1}, // The session times and contract details
+	{ProtocolVersion{Major: 6, Minor: 0}, int(FndOptionMultDeliv) + 1},   // The option premium multiplier and deliverable
+	{ProtocolVersion{}, int(FndExchangeRoot) + 1},
+}
+
+// fundamentalFields returns the number of fields of the layout used by protocol v. The zero version picks the newest
+// layout the line has enough fields for, for messages parsed without knowing the protocol of the connection.
+func fundamentalFields(v ProtocolVersion, items int) int {
+	for _, l := range fundamentalLayouts {
+		if v == (ProtocolVersion{}) {
+			if items >= l.fields {
+				return l.fields
+			}
+		} else if v.AtLeast(l.version.Major, l.version.Minor) {
+			return l.fields
+		}
+	}
+	return fundamentalLayouts[len(fundamentalLayouts)-1].fields
+}
+
+// UnMarshall sends the data into the usable struct for consumption by the application, a *ParseError is returned when
+// the line is too short or a field is invalid. The layout is picked by the number of fields sent, see UnMarshallProtocol
+// to parse the messages of a known protocol.
+func (f *FundamentalMsg) UnMarshall(d []byte, loc *time.Location) error {
+	return f.UnMarshallProtocol(d, loc, ProtocolVersion{})
+}
+
+// UnMarshallProtocol parses the message with the layout of protocol v, a line that is too short for the layout is a
+// *ParseError. Protocol 6.0 appends the option premium multiplier and multiple deliverable, 6.1 the session times and
+// contract details of futures and 6.2 the FIGI and security sub type.
+func (f *FundamentalMsg) UnMarshallProtocol(d []byte, loc *time.Location, v ProtocolVersion) error {
+	p := newFieldParser("FundamentalMsg", string(d), strings.Split(string(d), ","), loc)
+	f.unMarshallItems(p)
+	n := fundamentalFields(v, len(p.items))
+	if n > int(FndExchangeRoot)+1 {
+		f.OptionPremMult = p.float(55, "OptionPremMult")
+		f.OptionMultDeliv = p.int(56, "OptionMultDeliv")
+	}
+	if n > int(FndOptionMultDeliv)+1 {
+		f.SessionOpenTime = p.str(57, "SessionOpenTime")
+		f.SessionCloseTime = p.str(58, "SessionCloseTime")
+		f.BaseCurrency = p.str(59, "BaseCurrency")
+		f.ContractSize = p.str(60, "ContractSize")
+		f.ContractMonths = p.str(61, "ContractMonths")
+		f.MinTickSize = p.float(62, "MinTickSize")
+		f.FirstDeliveryDate = p.time(63, "FirstDeliveryDate", layoutMMDDCCYY)
+	}
+	if n > int(FndFirstDeliveryDate)+1 {
+		f.FIGI = p.str(64, "FIGI")
+		f.SecuritySubType = p.str(65, "SecuritySubType")
+	}
+	f.unMarshallDecimals(p)
+	f.Present, f.presentHigh = p.set[0], p.set[1]
+	return p.error()
+}
+
+func (f *FundamentalMsg) unMarshallItems(p *fieldParser) {
+	f.Symbol = p.str(0, "Symbol")                                          // APL,
+	f.ExchaangeID = p.str(1, "ExchaangeID")                                // 5,
+	f.PE = p.float(2, "PE")                                                // 9.9,
+	f.AvgVolume = p.int(3, "AvgVolume")                                    // 53599000,
+	f.Fifty2WkHigh = p.float(4, "Fifty2WkHigh")                            // 134.5400,
+	f.Fifty2WkLow = p.float(5, "Fifty2WkLow")                              // 92.0000,
+	f.CalYearHigh = p.float(6, "CalYearHigh")                              // 105.8500,
+	f.CalyearLow = p.float(7, "CalyearLow")                                // 92.3900,
+	f.DivYield = p.float(8, "DivYield")                                    // 2.2100,
+	f.DivAmt = p.float(9, "DivAmt")                                        // 0.5200,
+	f.DivRate = p.float(10, "DivRate")                                     // 2.0800,
+	f.PayDate = p.time(11, "PayDate", layoutMMDDCCYY)                      // 02/11/2016,
+	f.ExDivDate = p.time(12, "ExDivDate", layoutMMDDCCYY)                  // 02/04/2016,
+	f.Reserved1 = p.str(13, "Reserved1")                                   // ,
+	f.Reserved2 = p.str(14, "Reserved2")                                   // ,
+	f.Reserved3 = p.str(15, "Reserved3")                                   // ,
+	f.ShortInterest = p.int(16, "ShortInterest")                           // 63543520,
+	f.Reserved4 = p.str(17, "Reserved4")                                   // ,
+	f.CurrentYrEPS = p.float(18, "CurrentYrEPS")                           // 9.46,
+	f.NextYrEPS = p.float(19, "NextYrEPS")                                 // ,
+	f.FiveYrGrowthPct = p.float(20, "FiveYrGrowthPct")                     // 0.34,
+	f.FiscalYrEnd = p.int(21, "FiscalYrEnd")                               // 09,
+	f.Reserved5 = p.str(22, "Reserved5")                                   // ,
+	f.CompanyName = p.str(23, "CompanyName")                               // APPLE,
+	f.RootOptionSymbol = strings.Split(p.str(24, "RootOptionSymbol"), " ") // AAPL AAPL7,
+	f.PctHeldByInst = p.float(25, "PctHeldByInst")                         // 67.1,
+	f.Beta = p.float(26, "Beta")                                           // 1.35,
+	f.Leaps = p.str(27, "Leaps")                                           // ,
+	f.CurrentAssets = p.float(28, "CurrentAssets")                         // 89378.0,
+	f.CurrentLiabilities = p.float(29, "CurrentLiabilities")               // 80610.0,
+	f.BalSheetDate = p.time(30, "BalSheetDate", layoutMMDDCCYY)            // 12/31/2015,
+	f.LongTermDebt = p.float(31, "LongTermDebt")                           // 53463.0,
+	f.ComShrOutstanding = p.float(32, "ComShrOutstanding")                 // 5544583,
+	f.Reserved6 = p.str(33, "Reserved6")                                   // 334220,
+	f.SplitFactor1 = p.str(34, "SplitFactor1")                             // 0.14 06/09/2014,
+	f.SplitFactor2 = p.str(35, "SplitFactor2")                             // 0.50 02/28/2005,
+	f.Reserved7 = p.str(36, "Reserved7")                                   // ,
+	f.Reserved8 = p.str(37, "Reserved8")                                   // 0,
+	f.FormatCode = p.str(38, "FormatCode")                                 // 14,
+	f.Precision = p.int(39, "Precision")                                   // 4,
+	f.SIC = p.int(40, "SIC")                                               // 3571,
+	f.HistVolatility = p.float(41, "HistVolatility")                       // 36.98,
+	f.SecurityType = p.str(42, "SecurityType")                             // 1,
+	f.ListedMarket = p.str(43, "ListedMarket")                             // 21,
+	f.Fifty2WkHighDate = p.time(44, "Fifty2WkHighDate", layoutMMDDCCYY)    // 04/28/2015,
+	f.Fifty2WkLowDate = p.time(45, "Fifty2WkLowDate", layoutMMDDCCYY)      // 08/24/2015,
+	f.CalYearHighDate = p.time(46, "CalYearHighDate", layoutMMDDCCYY)      // 01/05/2016,
+	f.CalYearLowDate = p.time(47, "CalYearLowDate", layoutMMDDCCYY)        // 01/28/2016,
+	f.YrEndClose = p.float(48, "YrEndClose")                               // 105.26,
+	f.MaturityDate = p.time(49, "MaturityDate", layoutMMDDCCYY)            // ,
+	f.CouponRate = p.float(50, "CouponRate")                               // ,
+	f.ExpirationDate = p.time(51, "ExpirationDate", layoutMMDDCCYY)        // ,
+	f.StrikePrice = p.float(52, "StrikePrice")                             // ,
+	f.NAICS = p.int(53, "NAICS")                                           // 334220,
+	f.ExchangeRoot = p.str(54, "ExchangeRoot")                             // ,
 }
@@ -0,0 +1,293 @@
+package iqfeed
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IntervalType selects what an interval bar is built from.
+type IntervalType string
+
+const (
+	IntervalSeconds IntervalType = "s" // Interval is a number of seconds.
+	IntervalVolume  IntervalType = "v" // Interval is an amount of volume traded.
+	IntervalTicks   IntervalType = "t" // Interval is a number of trades.
+)
+
+// BarPeriod selects the period of a DailyRequest.
+type BarPeriod int
+
+const (
+	PeriodDaily BarPeriod = iota
+	PeriodWeekly
+	PeriodMonthly
+)
+
+// Tick is a single trade returned by a tick request (HTX/HTD/HTT).
+type Tick struct {
+	TimeStamp       time.Time // Time of the trade including microseconds.
+	Last            float64   // Trade price.
+	LastSize        int       // Trade size.
+	TotalVol        int       // Cumulative volume for the day after this trade.
+	Bid             float64   // Bid at the time of the trade.
+	Ask             float64   // Ask at the time of the trade.
+	TickID          int       // Identifier for the tick.
+	BasisForLast    string    // C for a last qualified trade, E for an extended trade.
+	TradeMktCenter  int       // Market Center of the trade, see Listed Market Codes.
+	TradeConditions string    // Conditions that identify the type of trade that occurred.
+}
+
+// UnMarshall sends the data into the usable struct for consumption by the application, a *ParseError is returned when
+// the row is too short or a field is invalid.
+func (t *Tick) UnMarshall(items []string, loc *time.Location) error {
+	p := newFieldParser("Tick", strings.Join(items, ","), items, loc)
+	t.TimeStamp = p.dateTime(0, "TimeStamp")
+	t.Last = p.float(1, "Last")
+	t.LastSize = p.int(2, "LastSize")
+	t.TotalVol = p.int(3, "TotalVol")
+	t.Bid = p.float(4, "Bid")
+	t.Ask = p.float(5, "Ask")
+	t.TickID = p.int(6, "TickID")
+	t.BasisForLast = p.str(7, "BasisForLast")
+	t.TradeMktCenter = p.int(8, "TradeMktCenter")
+	t.TradeConditions = p.str(9, "TradeConditions")
+	return p.error()
+}
+
+// Bar is a single interval bar returned by an interval request (HIX/HID/HIT).
+type Bar struct {
+	TimeStamp time.Time // Time label of the bar, the end of the interval unless LabelAtBeginning was requested.
+	High      float64
+	Low       float64
+	Open      float64
+	Close     float64
+	TotalVol  int // Cumulative volume for the day at the end of the bar.
+	PeriodVol int // Volume traded during the bar.
+	NumTrades int // Number of trades during the bar.
+}
+
+// UnMarshall sends the data into the usable struct for consumption by the application, a *ParseError is returned when
+// the row is too short or a field is invalid.
+func (b *Bar) UnMarshall(items []string, loc *time.Location) error {
+	p := newFieldParser("Bar", strings.Join(items, ","), items, loc)
+	b.TimeStamp = p.dateTime(0, "TimeStamp")
+	b.High = p.float(1, "High")
+	b.Low = p.float(2, "Low")
+	b.Open = p.float(3, "Open")
+	b.Close = p.float(4, "Close")
+	b.TotalVol = p.int(5, "TotalVol")
+	b.PeriodVol = p.int(6, "PeriodVol")
+	b.NumTrades = p.int(7, "NumTrades")
+	return p.error()
+}
+
+// DailyBar is a single daily, weekly or monthly bar returned by HDX/HDT/HWX/HMX requests.
+type DailyBar struct {
+	TimeStamp    time.Time // Date of the bar.
+	High         float64
+	Low          float64
+	Open         float64
+	Close        float64
+	PeriodVol    int // Volume traded during the period.
+	OpenInterest int // Open interest, futures and options only.
+}
+
+// UnMarshall sends the data into the usable struct for consumption by the application, a *ParseError is returned when
+// the row is too short or a field is invalid.
+func (b *DailyBar) UnMarshall(items []string, loc *time.Location) error {
+	p := newFieldParser("DailyBar", strings.Join(items, ","), items, loc)
+	b.TimeStamp = p.dateTime(0, "TimeStamp")
+	b.High = p.float(1, "High")
+	b.Low = p.float(2, "Low")
+	b.Open = p.float(3, "Open")
+	b.Close = p.float(4, "Close")
+	b.PeriodVol = p.int(5, "PeriodVol")
+	b.OpenInterest = p.int(6, "OpenInterest")
+	return p.error()
+}
+
+// TickRequest describes a tick request, Begin selects HTT (a date range), Days selects HTD (a number of days) and
+// otherwise HTX (the last MaxDatapoints ticks) is sent.
+type TickRequest struct {
+	Symbol            string
+	MaxDatapoints     int       // The maximum number of ticks to return, 0 for all.
+	Days              int       // The number of calendar days to return (HTD).
+	Begin             time.Time // Start of the range (HTT).
+	End               time.Time // End of the range (HTT), zero for now.
+	BeginFilter       string    // Only return ticks after this time of day each day in HHmmSS format.
+	EndFilter         string    // Only return ticks before this time of day each day in HHmmSS format.
+	OldestFirst       bool      // Return the oldest data first instead of the newest.
+	DatapointsPerSend int       // The number of datapoints IQConnect buffers before sending, 0 for the default.
+}
+
+func (r *TickRequest) command(id string, loc *time.Location) string {
+	dir := boolFlag(r.OldestFirst)
+	switch {
+	case !r.Begin.IsZero():
+		return fmt.Sprintf("HTT,%s,%s,%s,%s,%s,%s,%s,%s,%s\r\n", r.Symbol, optTime(r.Begin, loc), optTime(r.End, loc),
+			optInt(r.MaxDatapoints), r.BeginFilter, r.EndFilter, dir, id, optInt(r.DatapointsPerSend))
+	case r.Days > 0:
+		return fmt.Sprintf("HTD,%s,%d,%s,%s,%s,%s,%s,%s\r\n", r.Symbol, r.Days, optInt(r.MaxDatapoints),
+			r.BeginFilter, r.EndFilter, dir, id, optInt(r.DatapointsPerSend))
+	}
+	return fmt.Sprintf("HTX,%s,%s,%s,%s,%s\r\n", r.Symbol, optInt(r.MaxDatapoints), dir, id, optInt(r.DatapointsPerSend))
+}
+
+// IntervalRequest describes an interval bar request, Begin selects HIT, Days selects HID and otherwise HIX is sent.
+type IntervalRequest struct {
+	Symbol            string
+	Interval          int          // The size of each bar in units of Type.
+	Type              IntervalType // Defaults to IntervalSeconds.
+	MaxDatapoints     int          // The maximum number of bars to return, 0 for all.
+	Days              int          // The number of calendar days to return (HID).
+	Begin             time.Time    // Start of the range (HIT).
+	End               time.Time    // End of the range (HIT), zero for now.
+	BeginFilter       string       // Only return bars after this time of day each day in HHmmSS format.
+	EndFilter         string       // Only return bars before this time of day each day in HHmmSS format.
+	OldestFirst       bool         // Return the oldest data first instead of the newest.
+	DatapointsPerSend int          // The number of datapoints IQConnect buffers before sending, 0 for the default.
+	LabelAtBeginning  bool         // Label bars with the start of the interval instead of the end.
+}
+
+func (r *IntervalRequest) command(id string, loc *time.Location) string {
+	dir := boolFlag(r.OldestFirst)
+	typ := r.Type
+	if typ == "" {
+		typ = IntervalSeconds
+	}
+	tail := fmt.Sprintf("%s,%s,%s,%s", id, optInt(r.DatapointsPerSend), typ, boolFlag(r.LabelAtBeginning))
+	switch {
+	case !r.Begin.IsZero():
+		return fmt.Sprintf("HIT,%s,%d,%s,%s,%s,%s,%s,%s,%s\r\n", r.Symbol, r.Interval, optTime(r.Begin, loc), optTime(r.End, loc),
+			optInt(r.MaxDatapoints), r.BeginFilter, r.EndFilter, dir, tail)
+	case r.Days > 0:
+		return fmt.Sprintf("HID,%s,%d,%d,%s,%s,%s,%s,%s\r\n", r.Symbol, r.Interval, r.Days, optInt(r.MaxDatapoints),
+			r.BeginFilter, r.EndFilter, dir, tail)
+	}
+	return fmt.Sprintf("HIX,%s,%d,%s,%s,%s\r\n", r.Symbol, r.Interval, optInt(r.MaxDatapoints), dir, tail)
+}
+
+// DailyRequest describes a daily, weekly or monthly bar request. Daily bars over a date range are requested with HDT
+// when Begin is set, otherwise HDX, HWX or HMX return the last MaxDatapoints bars of the Period.
+type DailyRequest struct {
+	Symbol            string
+	Period            BarPeriod
+	MaxDatapoints     int       // The maximum number of bars to return, 0 for all.
+	Begin             time.Time // Start of the range, daily bars only (HDT).
+	End               time.Time // End of the range, daily bars only (HDT), zero for now.
+	OldestFirst       bool      // Return the oldest data first instead of the newest.
+	DatapointsPerSend int       // The number of datapoints IQConnect buffers before sending, 0 for the default.
+}
+
+func (r *DailyRequest) command(id string, loc *time.Location) string {
+	dir := boolFlag(r.OldestFirst)
+	tail := fmt.Sprintf("%s,%s,%s,%s", optInt(r.MaxDatapoints), dir, id, optInt(r.DatapointsPerSend))
+	switch r.Period {
+	case PeriodWeekly:
+		return fmt.Sprintf("HWX,%s,%s\r\n", r.Symbol, tail)
+	case PeriodMonthly:
+		return fmt.Sprintf("HMX,%s,%s\r\n", r.Symbol, tail)
+	}
+	if !r.Begin.IsZero() {
+		day := func(t time.Time) string {
+			if t.IsZero() {
+				return ""
+			}
+			return t.In(loc).Format("20060102")
+		}
+		return fmt.Sprintf("HDT,%s,%s,%s,%s\r\n", r.Symbol, day(r.Begin), day(r.End), tail)
+	}
+	return fmt.Sprintf("HDX,%s,%s\r\n", r.Symbol, tail)
+}
+
+// StreamTicks sends every tick matching the request on out as it is received and returns once the request is complete.
+func (l *LookupClient) StreamTicks(ctx context.Context, r *TickRequest, out chan<- *Tick) error {
+	return l.request(ctx, func(id string) string { return r.command(id, l.TimeLoc) }, func(items []string) error {
+		t := &Tick{}
+		if err := t.UnMarshall(items, l.TimeLoc); err != nil {
+			return err
+		}
+		select {
+		case out <- t:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// Ticks returns every tick matching the request.
+func (l *LookupClient) Ticks(ctx context.Context, r *TickRequest) ([]*Tick, error) {
+	var ticks []*Tick
+	err := l.request(ctx, func(id string) string { return r.command(id, l.TimeLoc) }, func(items []string) error {
+		t := &Tick{}
+		if err := t.UnMarshall(items, l.TimeLoc); err != nil {
+			return err
+		}
+		ticks = append(ticks, t)
+		return nil
+	})
+	return ticks, err
+}
+
+// StreamBars sends every interval bar matching the request on out as it is received and returns once the request is complete.
+func (l *LookupClient) StreamBars(ctx context.Context, r *IntervalRequest, out chan<- *Bar) error {
+	return l.request(ctx, func(id string) string { return r.command(id, l.TimeLoc) }, func(items []string) error {
+		b := &Bar{}
+		if err := b.UnMarshall(items, l.TimeLoc); err != nil {
+			return err
+		}
+		select {
+		case out <- b:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// Bars returns every interval bar matching the request.
+func (l *LookupClient) Bars(ctx context.Context, r *IntervalRequest) ([]*Bar, error) {
+	var bars []*Bar
+	err := l.request(ctx, func(id string) string { return r.command(id, l.TimeLoc) }, func(items []string) error {
+		b := &Bar{}
+		if err := b.UnMarshall(items, l.TimeLoc); err != nil {
+			return err
+		}
+		bars = append(bars, b)
+		return nil
+	})
+	return bars, err
+}
+
+// StreamDailyBars sends every daily, weekly or monthly bar matching the request on out and returns once the request is complete.
+func (l *LookupClient) StreamDailyBars(ctx context.Context, r *DailyRequest, out chan<- *DailyBar) error {
+	return l.request(ctx, func(id string) string { return r.command(id, l.TimeLoc) }, func(items []string) error {
+		b := &DailyBar{}
+		if err := b.UnMarshall(items, l.TimeLoc); err != nil {
+			return err
+		}
+		select {
+		case out <- b:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// DailyBars returns every daily, weekly or monthly bar matching the request.
+func (l *LookupClient) DailyBars(ctx context.Context, r *DailyRequest) ([]*DailyBar, error) {
+	var bars []*DailyBar
+	err := l.request(ctx, func(id string) string { return r.command(id, l.TimeLoc) }, func(items []string) error {
+		b := &DailyBar{}
+		if err := b.UnMarshall(items, l.TimeLoc); err != nil {
+			return err
+		}
+		bars = append(bars, b)
+		return nil
+	})
+	return bars, err
+}
@@ -1,43 +1,81 @@
+// Package iqfeed provides clients for the DTN IQFeed ports served by IQConnect: Level 1 (IQC), Level 2 (L2Client),
+// lookup (LookupClient), derivative (DerivClient) and admin (AdminClient).
 package iqfeed
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"io"
 	"log"
 	"net"
-	"os"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // IQC provides the main struct for the the IQ Client interface into what IQFeed will be sending us.
 type IQC struct {
-	System       chan *SystemMessage
-	News         chan *NewsMsg
-	Errors       chan *ErrorMsg
-	Fundamental  chan *FundamentalMsg
-	Regional     chan *RegionalMsg
-	Time         chan *TimeMsg
-	Updates      chan *UpdSummaryMsg
-	TimeZone     string
-	TimeLoc      *time.Location
-	CreateBackup bool
-	BackupFile   string
-	Conn         net.Conn
-	Quit         chan bool
-	DynFields    map[int]string
+	System      chan *SystemMessage
+	News        chan *NewsMsg
+	Errors      chan *ErrorMsg
+	Fundamental chan *FundamentalMsg
+	Regional    chan *RegionalMsg
+	Time        chan *TimeMsg
+	Updates     chan *UpdSummaryMsg
+	// DecimalPrices sets DecimalPrices on the update, fundamental and regional messages so their exact prices can be read
+	// with Decimal, see Price.
+	DecimalPrices bool
+	PoolUpdates   bool             // Reuses the messages sent on Updates, each must be given back with Release once used.
+	ParseErrors   chan *ParseError // Receives malformed messages that were dropped, buffered and dropped when full so it may be ignored.
+	TimeZone      string
+	TimeLoc       *time.Location
+	CreateBackup  bool
+	BackupFile    string
+	Capture       *CaptureWriter // Records the raw feed with rotation and compression, takes precedence over BackupFile.
+	Conn          net.Conn
+	DynFields     map[int]string
+	// Protocol is negotiated by Start when set (ex: 6.2), messages are parsed with the layout of the acknowledged protocol.
+	Protocol         string
+	HandshakeTimeout time.Duration // How long to wait for IQConnect to reply during the handshake, defaults to 5 seconds.
+	// AutoReconnect re-dials IQConnect with exponential backoff when the connection drops and restores
+	// the protocol, client name, update fields, watches, regional watches and news state that were active.
+	AutoReconnect bool
+	MaxBackoff    time.Duration                   // Upper bound for the reconnect backoff, defaults to 30 seconds.
+	Reconnects    chan *ReconnectMsg              // Receives a message after every reconnect when AutoReconnect is set, buffered and dropped when full so it may be ignored.
+	addr          string                          // The address we connected to, used when reconnecting.
+	connMu        sync.RWMutex                    // Guards Conn while it is replaced by a reconnect.
+	state         session                         // The session state we restore after a reconnect.
+	ctx           context.Context                 // Cancelled when the client is closed.
+	cancel        context.CancelFunc              // Cancels ctx.
+	wg            sync.WaitGroup                  // Tracks the goroutines started by Start.
+	waitMu        sync.Mutex                      // Guards waiters.
+	waiters       []*sysWaiter                    // Callers waiting for a system message reply, see expect.
+	version       atomic.Pointer[ProtocolVersion] // The protocol acknowledged by IQConnect.
+	updColumns    []updColumn                     // The parsers for the positions of DynFields, see mapUpdateFields.
+	strs          stringCache                     // The strings shared by the update messages, only used by the reader.
+	feedTime      time.Time                       // The latest T message, used to date the time-only fields.
 }
 
-func (c *IQC) connect(cs string) {
-	if c.TimeZone == "" {
-		c.TimeZone = "America/New_York"
+// LoadTimeLoc returns loc when it is already set, otherwise it loads the time zone which defaults to America/New_York.
+func loadTimeLoc(tz *string, loc *time.Location) (*time.Location, error) {
+	if loc != nil {
+		return loc, nil
 	}
+	if *tz == "" {
+		*tz = "America/New_York"
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		// We absolutely need the timezone / location so we cannot continue without it.
+		return nil, &TimeZoneError{TimeZone: *tz, Err: err}
+	}
+	return loc, nil
+}
+
+func (c *IQC) connect(cs string) error {
 	var err error
-	c.TimeLoc, err = time.LoadLocation(c.TimeZone)
+	c.TimeLoc, err = loadTimeLoc(&c.TimeZone, c.TimeLoc)
 	if err != nil {
-		// We absolutely need the timezone / location so we must panic if it fails.
-		panic(err)
+		return err
 	}
 	c.DynFields = make(map[int]string)
 	if cs == "" {
@@ -45,100 +83,181 @@ func (c *IQC) connect(cs string) {
 	}
 	conn, err := net.Dial("tcp", cs)
 	if err != nil {
-		log.Fatal("Could not connect to IQFeed")
+		return &DialError{Addr: cs, Err: err}
 	}
-	c.Conn = conn
+	c.addr = cs
+	c.setConn(conn)
+	return nil
 }
 
 // ProcessSysMsg handles system messages, field definitions are available here: http://www.iqfeed.net/dev/api/docs/Level1SystemMessage.cfm.
 func (c *IQC) processSysMsg(d []byte) {
 	s := &SystemMessage{}
-
-	pfx := strings.Split(string(d), ",")
-	switch pfx[0] {
-	case "UPDATE FIELDNAMES":
-		/* We use a map here to preserve the actual order as it's important with marshalling dynamic fields */
-		for i := 1; i < len(pfx); i++ {
-			c.DynFields[i-1] = pfx[i]
-		}
-	case "CURRENT UPDATE FIELDNAMES":
+	if err := s.UnMarshall(d, c.TimeLoc); err != nil {
+		reportParseError(c.ParseErrors, err)
+		return
+	}
+	switch s.Type {
+	case SysCurrentUpdateFieldNames:
 		/* We use a map here to preserve the actual order as it's important with marshalling dynamic fields */
-		for i := 1; i < len(pfx); i++ {
-			c.DynFields[i-1] = pfx[i]
+		c.DynFields = make(map[int]string, len(s.Fields))
+		for i, f := range s.Fields {
+			c.DynFields[i] = f
 		}
-	default:
-		s.UnMarshall(d, c.TimeLoc)
-		c.System <- s
+		c.mapUpdateFields()
+		c.deliverSys(s)
+		return
+	case SysUpdateFieldNames:
+		// The fields available for the protocol, the fieldset of the connection is unchanged.
+		c.deliverSys(s)
+		return
+	case SysCurrentProtocol:
+		c.setVersion(s.Protocol)
+	}
+	if c.deliverSys(s) {
+		// The reply was requested by a caller that is waiting for it.
+		return
+	}
+	select {
+	case c.System <- s:
+	case <-c.ctx.Done():
+	}
+}
+
+// NewUpdSummaryMsg returns an empty message, taken from the pool when PoolUpdates is set, requesting the exact prices
+// when DecimalPrices is set.
+func (c *IQC) newUpdSummaryMsg() *UpdSummaryMsg {
+	if !c.PoolUpdates {
+		return &UpdSummaryMsg{DecimalPrices: c.DecimalPrices}
 	}
+	u := getUpdSummaryMsg()
+	u.DecimalPrices = c.DecimalPrices
+	return u
 }
 
 // ProcessSumMsg handles summary messages, field definitions are available here: http://www.iqfeed.net/dev/api/docs/Level1UpdateSummaryMessage.cfm.
 func (c *IQC) processSumMsg(d []byte) {
-	s := &UpdSummaryMsg{}
-	items := strings.Split(string(d), ",")
-	s.UnMarshall(items, c.DynFields, c.TimeLoc)
-	c.Updates <- s
+	s := c.newUpdSummaryMsg()
+	if err := s.unMarshallLine(d, c.updColumns, c.TimeLoc, &c.strs); err != nil {
+		s.Release()
+		reportParseError(c.ParseErrors, err)
+		return
+	}
+	s.AttachDate(c.currentFeedTime())
+	select {
+	case c.Updates <- s:
+	case <-c.ctx.Done():
+	}
 }
 
 // ProcessUpdMsg handles update messages, field definitions are available here: http://www.iqfeed.net/dev/api/docs/Level1UpdateSummaryMessage.cfm.
 func (c *IQC) processUpdMsg(d []byte) {
-	u := &UpdSummaryMsg{}
-	items := strings.Split(string(d), ",")
-	if items[2] == "Not Found" {
-		c.process404Msg([]byte(items[0]))
+	if string(nthField(d, 2)) == "Not Found" {
+		c.process404Msg(nthField(d, 0))
+		return
+	}
+	u := c.newUpdSummaryMsg()
+	if err := u.unMarshallLine(d, c.updColumns, c.TimeLoc, &c.strs); err != nil {
+		u.Release()
+		reportParseError(c.ParseErrors, err)
 		return
 	}
-	u.UnMarshall(items, c.DynFields, c.TimeLoc)
-	c.Updates <- u
+	u.AttachDate(c.currentFeedTime())
+	select {
+	case c.Updates <- u:
+	case <-c.ctx.Done():
+	}
 }
 
 // ProcessTimeMsg handles timestamp updates, field definitions are available here: http://www.iqfeed.net/dev/api/docs/TimeMessageFormat.cfm.
 func (c *IQC) processTimeMsg(d []byte) {
 	t := &TimeMsg{}
-	t.UnMarshall(d, c.TimeLoc)
+	if err := t.UnMarshall(d, c.TimeLoc); err != nil {
+		reportParseError(c.ParseErrors, err)
+		return
+	}
+	c.feedTime = t.TimeStamp
+	select {
+	case c.Time <- t:
+	case <-c.ctx.Done():
+	}
+}
 
-	c.Time <- t
+// CurrentFeedTime returns the latest T message, a live connection uses the wall clock until the first one is received.
+// Replays have no fallback as the wall clock has nothing to do with the recorded session.
+func (c *IQC) currentFeedTime() time.Time {
+	if c.feedTime.IsZero() && c.conn() != nil {
+		return time.Now().In(c.TimeLoc)
+	}
+	return c.feedTime
 }
 
 // ProcessRegUpdMsg handles regional updates field definitions are available here: http://www.iqfeed.net/dev/api/docs/RegionalMessageFormat.cfm.
 func (c *IQC) processRegUpdMsg(d []byte) {
-	r := &RegionalMsg{}
-	r.UnMarshall(d, c.TimeLoc)
-	c.Regional <- r
+	r := &RegionalMsg{DecimalPrices: c.DecimalPrices}
+	if err := r.UnMarshall(d, c.TimeLoc); err != nil {
+		reportParseError(c.ParseErrors, err)
+		return
+	}
+	r.AttachDate(c.currentFeedTime())
+	select {
+	case c.Regional <- r:
+	case <-c.ctx.Done():
+	}
 }
 
 // ProcessFndMsg handles fundamental messages, field descriptions are available here: http://www.iqfeed.net/dev/api/docs/Level1FundamentalMessage.cfm.
 func (c *IQC) processFndMsg(d []byte) {
-	f := &FundamentalMsg{}
-	f.UnMarshall(d, c.TimeLoc)
-	c.Fundamental <- f
-
+	f := &FundamentalMsg{DecimalPrices: c.DecimalPrices}
+	if err := f.UnMarshallProtocol(d, c.TimeLoc, c.ProtocolVersion()); err != nil {
+		reportParseError(c.ParseErrors, err)
+		return
+	}
+	select {
+	case c.Fundamental <- f:
+	case <-c.ctx.Done():
+	}
 }
 
 // ProcessNewsMsg handles summary messages, field definitions are available here: http://www.iqfeed.net/dev/api/docs/StreamingNewsMessageFormat.cfm.
 func (c *IQC) processNewsMsg(d []byte) {
 	n := &NewsMsg{}
-	n.UnMarshall(d, c.TimeLoc)
-	c.News <- n
+	if err := n.UnMarshall(d, c.TimeLoc); err != nil {
+		reportParseError(c.ParseErrors, err)
+		return
+	}
+	select {
+	case c.News <- n:
+	case <-c.ctx.Done():
+	}
 }
 
 // Process404Msg handles messages indicating that a symbol was not found.
 func (c *IQC) process404Msg(d []byte) {
 	e := &ErrorMsg{}
 	e.UnMarshall(true, d, 404)
-	c.Errors <- e
+	select {
+	case c.Errors <- e:
+	case <-c.ctx.Done():
+	}
 }
 
 // ProcessErrorMsg handles error messages in the form of error text.
 func (c *IQC) processErrorMsg(d []byte) {
 	e := &ErrorMsg{}
 	e.UnMarshall(false, d, 500)
-	c.Errors <- e
+	if c.deliverError(e) {
+		return
+	}
+	select {
+	case c.Errors <- e:
+	case <-c.ctx.Done():
+	}
 }
 
 // ProcessReceiver is one of the main reciever functions that interprets data received by IQFeed and processes it in sub functions.
 func (c *IQC) processReceiver(d []byte) {
-	if d == nil || len(d) <3 {
+	if d == nil || len(d) < 3 {
 		return
 	}
 	data := d[2:]
@@ -162,107 +281,95 @@ func (c *IQC) processReceiver(d []byte) {
 	case 0x45: // Start letter is E, error message
 		c.processErrorMsg(data)
 	default: // Print unknown message types
-		fmt.Printf("Unknown message type: %#v\n", d[0])		
+		fmt.Printf("Unknown message type: %#v\n", d[0])
 	}
 
 }
 
-// Read function does as expected and reads data from the network stream.
-func (c *IQC) read() {
-	r := bufio.NewReader(c.Conn)
+// Read function does as expected and reads data from the network stream until the client is closed, the output channels
+// are closed once it returns as it is the only goroutine sending on them.
+func (c *IQC) read(fc *feedConn) {
+	defer c.wg.Done()
+	defer c.closeChannels()
+	defer func() {
+		if c.Capture != nil {
+			c.Capture.Close()
+		}
+	}()
 	for {
-		select {
-		case <-c.Quit:
+		err := c.readConn(fc)
+		if c.ctx.Err() != nil {
 			log.Println("Client quitting")
-			c.Conn.Close()
-			break
-		default:
-			line, isPrefix, err := r.ReadLine()
-			for err == nil && !isPrefix {
-				if c.CreateBackup {
-					bld := fmt.Sprintf("%s\r\n", string(line))
-					c.writeBackup([]byte(bld))
-				}
-				c.processReceiver(line)
-				line, isPrefix, err = r.ReadLine()
-			}
-			if isPrefix {
-				log.Println("buffer size to small")
-				//return Do not return and break the loop
-			}
-			if err != io.EOF {
-				log.Println("Pipe closed exiting...")
-				c.Conn.Close()
-				os.Exit(0)
-			}
+			return
+		}
+		if c.AutoReconnect {
+			fc = c.reconnect(err)
+		}
+		if !c.AutoReconnect || fc == nil {
+			log.Println("Pipe closed exiting...")
+			c.cancel()
+			return
 		}
 	}
+}
 
+// CloseChannels closes every output channel, it must only be called by the reader once it has stopped sending.
+func (c *IQC) closeChannels() {
+	close(c.System)
+	close(c.News)
+	close(c.Errors)
+	close(c.Fundamental)
+	close(c.Regional)
+	close(c.Time)
+	close(c.Updates)
+	close(c.ParseErrors)
+	if c.Reconnects != nil {
+		close(c.Reconnects)
+	}
 }
 
-func (c *IQC) getCallChar(t time.Time) string {
-	switch t.Month().String() {
-	case "January":
-		return "A"
-	case "February":
-		return "B"
-	case "March":
-		return "C"
-	case "April":
-		return "D"
-	case "May":
-		return "E"
-	case "June":
-		return "F"
-	case "July":
-		return "G"
-	case "August":
-		return "H"
-	case "September":
-		return "I"
-	case "October":
-		return "J"
-	case "November":
-		return "K"
-	case "December":
-		return "L"
-	}
-	return "A"
+// Close stops the client, unblocking the reader and closing the connection to IQConnect. It returns once every goroutine
+// started by Start has exited and all output channels have been closed, it is safe to call more than once.
+func (c *IQC) Close() error {
+	if c.cancel == nil {
+		return nil
+	}
+	c.cancel()
+	c.wg.Wait()
+	return nil
 }
 
-func (c *IQC) getPutChar(t time.Time) string {
-	switch t.Month().String() {
-	case "January":
-		return "M"
-	case "February":
-		return "N"
-	case "March":
-		return "O"
-	case "April":
-		return "P"
-	case "May":
-		return "Q"
-	case "June":
-		return "R"
-	case "July":
-		return "S"
-	case "August":
-		return "T"
-	case "September":
-		return "U"
-	case "October":
-		return "V"
-	case "November":
-		return "W"
-	case "December":
-		return "X"
-	}
-	return "M"
+// ReadConn processes the lines held back by the handshake, then reads and processes lines from the connection until it
+// returns an error.
+func (c *IQC) readConn(fc *feedConn) error {
+	for _, line := range fc.held {
+		c.receive(line)
+	}
+	fc.held = nil
+	for {
+		line, isPrefix, err := fc.r.ReadLine()
+		if err != nil {
+			return err
+		}
+		if isPrefix {
+			log.Println("buffer size to small")
+			continue // Do not return and break the loop
+		}
+		c.receive(line)
+	}
 }
 
-// Start function will start the concurrent functions to read and write data to the and from the network stream.
-func (c *IQC) Start(connectString string) *IQC {
-	c.connect(connectString)
+// Receive records the line when a backup is requested and processes it.
+func (c *IQC) receive(line []byte) {
+	if c.CreateBackup || c.Capture != nil {
+		c.writeBackup(line)
+	}
+	c.processReceiver(line)
+}
+
+// Init creates the output channels and the context that controls the lifetime of the client.
+func (c *IQC) init(ctx context.Context) {
+	c.ctx, c.cancel = context.WithCancel(ctx)
 	c.System = make(chan *SystemMessage)
 	c.News = make(chan *NewsMsg)
 	c.Errors = make(chan *ErrorMsg)
@@ -270,9 +377,55 @@ func (c *IQC) Start(connectString string) *IQC {
 	c.Regional = make(chan *RegionalMsg)
 	c.Time = make(chan *TimeMsg)
 	c.Updates = make(chan *UpdSummaryMsg)
-	go c.read()
-	c.ReqCurrentUpdateFNames()
-	c.RequestListedMarkets()
-	return c
+	c.ParseErrors = make(chan *ParseError, 100)
+	if c.AutoReconnect {
+		c.Reconnects = make(chan *ReconnectMsg, 16)
+	}
+}
 
+// Start function will start the concurrent functions to read and write data to the and from the network stream.
+// An error is returned if the time zone cannot be loaded (*TimeZoneError), IQConnect cannot be reached (*DialError)
+// or the initial requests cannot be completed (*HandshakeError), allowing the caller to retry rather than exit.
+// When Protocol is set Start only returns once IQConnect has acknowledged it. Cancelling ctx has the same effect as calling Close.
+func (c *IQC) Start(ctx context.Context, connectString string) (*IQC, error) {
+	if err := c.connect(connectString); err != nil {
+		return nil, err
+	}
+	c.init(ctx)
+	// The protocol is set before the reader starts so the field names below are already sent in its layout, and a failed
+	// handshake leaves nothing running.
+	fc, err := c.handshake(c.conn(), c.Protocol)
+	if err != nil {
+		c.cancel()
+		c.conn().Close()
+		c.closeChannels()
+		return nil, err
+	}
+	if c.Protocol != "" {
+		c.state.setProtocol(c.ProtocolVersion().String())
+	}
+	c.wg.Add(2)
+	go c.read(fc)
+	go func() {
+		// Closing the connection is the only way to unblock a pending read.
+		defer c.wg.Done()
+		<-c.ctx.Done()
+		c.conn().Close()
+	}()
+	if err := c.write("S,REQUEST CURRENT UPDATE FIELDNAMES\r\n"); err != nil {
+		c.Close()
+		return nil, &HandshakeError{Command: "S,REQUEST CURRENT UPDATE FIELDNAMES", Err: err}
+	}
+	return c, nil
+}
+
+// MustStart keeps the behaviour of the former Start(connectString) *IQC: it starts the client without a context and exits
+// the program when IQConnect cannot be reached.
+//
+// Deprecated: Use Start, which returns the error so the caller can retry.
+func (c *IQC) MustStart(connectString string) *IQC {
+	if _, err := c.Start(context.Background(), connectString); err != nil {
+		log.Fatal(err)
+	}
+	return c
 }
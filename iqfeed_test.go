@@ -1,15 +1,609 @@
 package iqfeed
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nitecon/iqfeed/iqfeedtest"
+)
+
+// fundamentalLine returns an F message for symbol with every other field left empty.
+func fundamentalLine(symbol string) string {
+	return "F," + symbol + strings.Repeat(",", 55)
+}
 
 func TestStart(t *testing.T) {
-	/*dataChan := make(chan []byte)
-	c, err := net.Dial("tcp", "127.0.0.1:5009")
-	if err != nil {
-		log.Fatal("Could not connect to IQFeed")
+	srv := iqfeedtest.NewServer()
+	defer srv.Close()
+	srv.TimeInterval = 50 * time.Millisecond
+	srv.Script("AAPL", fundamentalLine("AAPL"),
+		"P,AAPL,95.0200,100,09:35:57.022,26,1325032,95.0200,100,95.0400,400,95.0000,95.3800,94.8600,94.4800,ba,01,")
+
+	c := &IQC{TimeZone: "America/New_York"}
+	if _, err := c.Start(context.Background(), srv.Addr); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	defer c.Close()
+	c.WatchSymbol("AAPL")
+	c.WatchSymbol("NOPE")
+
+	var gotFnd, gotSum, gotUpd, gotTime, gotErr bool
+	timeout := time.After(5 * time.Second)
+	for !(gotFnd && gotSum && gotUpd && gotTime && gotErr) {
+		select {
+		case f := <-c.Fundamental:
+			if f.Symbol != "AAPL" {
+				t.Errorf("fundamental symbol = %q", f.Symbol)
+			}
+			gotFnd = true
+		case u := <-c.Updates:
+			if u.Symbol != "AAPL" || u.Bid != 95.02 || u.AskSize != 400 {
+				t.Errorf("unexpected update %+v", u)
+			}
+			if !gotSum {
+				gotSum = true
+				srv.Send("Q,AAPL,95.0300,200,09:35:58.001,26,1325232,95.0200,100,95.0400,400,95.0000,95.3800,94.8600,94.4800,C,01,")
+				continue
+			}
+			if u.TotalVol != 1325232 {
+				t.Errorf("update total volume = %d", u.TotalVol)
+			}
+			gotUpd = true
+		case <-c.Time:
+			gotTime = true
+		case e := <-c.Errors:
+			if e.Code != 404 {
+				t.Errorf("unexpected error %+v", e)
+			}
+			gotErr = true
+		case <-c.System:
+		case <-timeout:
+			t.Fatalf("timed out: fundamental %t, summary %t, update %t, time %t, not found %t", gotFnd, gotSum, gotUpd, gotTime, gotErr)
+		}
+	}
+	if c.DynFields[0] != "Symbol" || len(c.DynFields) != len(iqfeedtest.DefaultUpdateFields) {
+		t.Errorf("DynFields = %v", c.DynFields)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	srv := iqfeedtest.NewServer()
+	defer srv.Close()
+
+	c := &IQC{AutoReconnect: true}
+	if _, err := c.Start(context.Background(), srv.Addr); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	defer c.Close()
+	c.SelectUpdateFields("Bid", "Ask")
+	c.WatchSymbol("AAPL")
+	if !srv.WaitCommand("wAAPL", 1, 5*time.Second) {
+		t.Fatal("watch was not received")
+	}
+	srv.Disconnect()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case r := <-c.Reconnects:
+			if r.Attempts < 1 || r.Up.Before(r.Down) {
+				t.Errorf("unexpected reconnect %+v", r)
+			}
+			if !srv.WaitCommand("wAAPL", 2, 5*time.Second) || !srv.WaitCommand("S,SELECT UPDATE FIELDS,Bid,Ask", 2, time.Second) {
+				t.Fatalf("session was not restored: %v", srv.Commands())
+			}
+			return
+		case <-c.Errors:
+		case <-c.System:
+		case <-timeout:
+			t.Fatal("timed out waiting for the reconnect")
+		}
+	}
+}
+
+func TestReconnectUnread(t *testing.T) {
+	srv := iqfeedtest.NewServer()
+	defer srv.Close()
+
+	c := &IQC{AutoReconnect: true}
+	if _, err := c.Start(context.Background(), srv.Addr); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	defer c.Close()
+	// Nobody reads Reconnects, a full buffer must not stop the updates after the reconnect.
+	for len(c.Reconnects) < cap(c.Reconnects) {
+		c.Reconnects <- &ReconnectMsg{}
+	}
+	if !srv.WaitCommand("S,REQUEST CURRENT UPDATE FIELDNAMES", 1, 5*time.Second) {
+		t.Fatal("handshake was not received")
+	}
+	srv.Disconnect()
+	if !srv.WaitCommand("S,REQUEST CURRENT UPDATE FIELDNAMES", 2, 5*time.Second) {
+		t.Fatal("client did not reconnect")
+	}
+	srv.Send("Q,AAPL,95.0300,200,09:35:58.001,26,1325232,95.0200,100,95.0400,400,95.0000,95.3800,94.8600,94.4800,C,01,")
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case u := <-c.Updates:
+			if u.Symbol != "AAPL" {
+				t.Errorf("unexpected update %+v", u)
+			}
+			return
+		case <-c.System:
+		case <-c.Errors:
+		case <-timeout:
+			t.Fatal("updates stopped after the reconnect")
+		}
+	}
+}
+
+func TestReconnectProtocol(t *testing.T) {
+	srv := iqfeedtest.NewServer()
+	defer srv.Close()
+
+	c := &IQC{Protocol: "6.2", AutoReconnect: true, MaxBackoff: minBackoff}
+	if _, err := c.Start(context.Background(), srv.Addr); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	defer c.Close()
+	if !srv.WaitCommand("S,REQUEST CURRENT UPDATE FIELDNAMES", 1, 5*time.Second) {
+		t.Fatal("handshake was not received")
+	}
+	// The restarted IQConnect only speaks 5.1, the redials must be rejected before the session is replayed.
+	srv.SetProtocol("5.1")
+	srv.Disconnect()
+	if !srv.WaitCommand("S,SET PROTOCOL,6.2", 3, 5*time.Second) {
+		t.Fatalf("client did not redial: %v", srv.Commands())
+	}
+	if srv.WaitCommand("S,REQUEST CURRENT UPDATE FIELDNAMES", 2, 0) {
+		t.Fatalf("the session was replayed on a connection with the wrong protocol: %v", srv.Commands())
+	}
+	select {
+	case r := <-c.Reconnects:
+		t.Fatalf("reconnected with the wrong protocol: %+v", r)
+	default:
 	}
 
+	srv.SetProtocol("")
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case <-c.Reconnects:
+			if v := c.ProtocolVersion(); v != (ProtocolVersion{Major: 6, Minor: 2}) {
+				t.Errorf("ProtocolVersion() = %s after the reconnect", v)
+			}
+			return
+		case <-c.System:
+		case <-timeout:
+			t.Fatal("timed out waiting for the reconnect")
+		}
+	}
+}
+
+func TestNegotiateProtocol(t *testing.T) {
+	srv := iqfeedtest.NewServer()
+	defer srv.Close()
+
+	c := &IQC{Protocol: "6.2"}
+	if _, err := c.Start(context.Background(), srv.Addr); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	if v := c.ProtocolVersion(); v != (ProtocolVersion{Major: 6, Minor: 2}) {
+		t.Errorf("ProtocolVersion() = %s", v)
+	}
 	c.Close()
-	*/
 
+	srv.Protocol = "5.1"
+	c = &IQC{Protocol: "6.2"}
+	_, err := c.Start(context.Background(), srv.Addr)
+	var perr *ProtocolError
+	if !errors.As(err, &perr) || perr.Current != "5.1" {
+		t.Errorf("Start with an unsupported protocol returned %v", err)
+	}
+	// Nothing is left running after the failed handshake.
+	select {
+	case _, ok := <-c.System:
+		if ok {
+			t.Error("a system message was sent after the failed handshake")
+		}
+	case <-time.After(time.Second):
+		t.Error("the channels were not closed after the failed handshake")
+	}
+	if updateFieldName("Most Recent Trade TimeMS", ProtocolVersion{Major: 5, Minor: 1}) != "Most Recent Trade Time" {
+		t.Error("5.x TimeMS fields are not mapped to the 6.x names")
+	}
+}
+
+func TestDeliverError(t *testing.T) {
+	c := &IQC{}
+	proto := c.expect(SysCurrentProtocol, "S,SET PROTOCOL,6.2")
+	fields := c.expect(SysCurrentUpdateFieldNames, "S,SELECT UPDATE FIELDS,Bid,Last Size")
+	// Errors that do not name a pending command are left for Errors.
+	if c.deliverError(&ErrorMsg{Code: 500, Message: "Invalid symbol: ZZZZ"}) {
+		t.Error("an unrelated error was delivered to a waiter")
+	}
+	// The error goes to the command it names even when an older command is waiting.
+	if !c.deliverError(&ErrorMsg{Code: 500, Message: "Invalid field name: Last Size"}) {
+		t.Fatal("the error was not delivered")
+	}
+	select {
+	case r := <-fields.ch:
+		if r.err == nil || r.err.Message != "Invalid field name: Last Size" {
+			t.Errorf("reply = %+v", r)
+		}
+	default:
+		t.Error("the error was not delivered to the SELECT UPDATE FIELDS waiter")
+	}
+	if len(proto.ch) != 0 || len(c.waiters) != 1 || c.waiters[0] != proto {
+		t.Error("the SET PROTOCOL waiter was not left waiting")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	srv := iqfeedtest.NewServer()
+	defer srv.Close()
+	srv.Script("AAPL", "F,AAPL,5,9.9", "R,AAPL,5,95.02,abc", "Q,AAPL,95.0200,100,09:35:57.022,26,1325032")
+
+	c := &IQC{}
+	if _, err := c.Start(context.Background(), srv.Addr); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	defer c.Close()
+	c.WatchSymbol("AAPL")
+
+	var perrs []*ParseError
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case perr := <-c.ParseErrors:
+			perrs = append(perrs, perr)
+			continue
+		case u := <-c.Updates:
+			if u.Symbol != "AAPL" {
+				t.Errorf("unexpected update %+v", u)
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for the update after the malformed messages")
+		}
+		break
+	}
+	// The errors are buffered so the last one may still be waiting once the update has been received.
+	for len(c.ParseErrors) > 0 {
+		perrs = append(perrs, <-c.ParseErrors)
+	}
+	if len(perrs) != 2 {
+		t.Fatalf("got %d parse errors, want 2: %v", len(perrs), perrs)
+	}
+	if perrs[0].Message != "FundamentalMsg" || perrs[0].Field != "AvgVolume" || perrs[0].Raw != "AAPL,5,9.9" {
+		t.Errorf("unexpected fundamental error %v", perrs[0])
+	}
+	if perrs[1].Message != "RegionalMsg" || perrs[1].Field != "RegBidSize" || perrs[1].Value != "abc" {
+		t.Errorf("unexpected regional error %v", perrs[1])
+	}
+}
+
+func TestFieldPresence(t *testing.T) {
+	u := &UpdSummaryMsg{}
+	fields := map[int]string{0: "Symbol", 1: "Bid", 2: "Ask", 3: "Bid Size"}
+	if err := u.UnMarshall([]string{"AAPL", "", "0.00", "0"}, fields, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	if u.Has(FieldBid) || !u.Has(FieldAsk) || !u.Has(FieldBidSize) || u.Has(FieldLast) {
+		t.Errorf("Present = %v", u.Present.Fields())
+	}
+	f := &FundamentalMsg{}
+	if err := f.UnMarshall([]byte(strings.TrimPrefix(fundamentalLine("AAPL"), "F,")), time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Has(FndSymbol) || f.Has(FndPE) {
+		t.Errorf("Present = %b", f.Present)
+	}
+}
+
+func TestAttachDate(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	ref := time.Date(2024, time.March, 5, 0, 0, 2, 0, loc)
+	u := &UpdSummaryMsg{
+		BidTime:             GetTimeInHMS("23:59:59.5", loc),
+		AskTime:             GetTimeInHMS("00:00:01", loc),
+		MostRecntTradeDate:  GetDateMMDDCCYY("03/01/2024", loc),
+		MostRecentTradeTime: GetTimeInHMS("15:59:59.123456", loc),
+	}
+	u.AttachDate(ref)
+	if want := time.Date(2024, time.March, 4, 23, 59, 59, 5e8, loc); !u.BidTime.Equal(want) {
+		t.Errorf("BidTime = %s, want %s", u.BidTime, want)
+	}
+	if want := time.Date(2024, time.March, 5, 0, 0, 1, 0, loc); !u.AskTime.Equal(want) {
+		t.Errorf("AskTime = %s, want %s", u.AskTime, want)
+	}
+	if want := time.Date(2024, time.March, 1, 15, 59, 59, 123456000, loc); !u.MostRecentTradeTime.Equal(want) {
+		t.Errorf("MostRecentTradeTime = %s, want %s", u.MostRecentTradeTime, want)
+	}
+	if !u.TradeTime.IsZero() {
+		t.Errorf("unset TradeTime became %s", u.TradeTime)
+	}
+}
+
+func TestPrice(t *testing.T) {
+	for _, s := range []string{"95.0200", "-0.05", "1200", "0.000125"} {
+		p, err := ParsePrice(s)
+		if err != nil || p.String() != s {
+			t.Errorf("ParsePrice(%s) = %s, %v", s, p, err)
+		}
+	}
+	a, _ := ParsePrice("0.1")
+	b, _ := ParsePrice("0.20")
+	if sum, ok := a.Add(b); !ok || sum.String() != "0.30" || sum.Cmp(Price{Value: 3, Scale: 1}) != 0 {
+		t.Errorf("0.1 + 0.20 = %s, %t", sum, ok)
+	}
+	if diff, ok := a.Sub(b); !ok || diff.String() != "-0.10" {
+		t.Errorf("0.1 - 0.20 = %s, %t", diff, ok)
+	}
+	// The largest value cannot be rescaled to the scale of the other price.
+	huge := Price{Value: math.MaxInt64}
+	if _, ok := huge.Add(b); ok {
+		t.Error("Add did not report the overflow")
+	}
+	if _, ok := huge.Add(Price{Value: 1}); ok {
+		t.Error("Add did not report the overflow of the sum")
+	}
+	if _, ok := (Price{Value: math.MinInt64}).Sub(Price{Value: 1}); ok {
+		t.Error("Sub did not report the overflow of the difference")
+	}
+	if huge.Cmp(b) != 1 || b.Cmp(huge) != -1 || (Price{Value: -math.MaxInt64}).Cmp(b) != -1 {
+		t.Error("Cmp of a price too large to rescale is wrong")
+	}
+	if _, err := ParsePrice("1.2.3"); err == nil {
+		t.Error("ParsePrice accepted 1.2.3")
+	}
+
+	u := &UpdSummaryMsg{DecimalPrices: true}
+	fields := map[int]string{0: "Symbol", 1: "Bid", 2: "Decimal Precision", 3: "Total Volume"}
+	if err := u.UnMarshall([]string{"AAPL", "95.02", "4", "100"}, fields, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := u.Decimal(FieldBid); !ok || d != (Price{Value: 950200, Scale: 4}) {
+		t.Errorf("Decimal(FieldBid) = %v, %t", d, ok)
+	}
+	if _, ok := u.Decimal(FieldTotalVolume); ok {
+		t.Error("Total Volume was kept as a price")
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	for _, name := range iqfeedtest.AllUpdateFields {
+		if _, ok := LookupUpdField(name); !ok {
+			t.Errorf("no UpdField for %q", name)
+		}
+	}
+	srv := iqfeedtest.NewServer()
+	defer srv.Close()
+
+	c := &IQC{}
+	if _, err := c.Start(context.Background(), srv.Addr); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	defer c.Close()
+	if err := c.SelectFields(context.Background(), FieldBid, FieldLastSize); err != nil {
+		t.Fatalf("SelectFields: %s", err)
+	}
+	if c.DynFields[1] != "Bid" || c.DynFields[2] != "Last Size" || len(c.DynFields) != 3 {
+		t.Errorf("DynFields = %v", c.DynFields)
+	}
+	var ferr *FieldError
+	if err := c.SelectFields(context.Background(), FieldAsk, FieldIncrementalVolume); !errors.As(err, &ferr) ||
+		len(ferr.Fields) != 1 || ferr.Fields[0] != FieldIncrementalVolume {
+		t.Errorf("SelectFields with a field that is not offered returned %v", err)
+	}
+	for _, cmd := range srv.Commands() {
+		if strings.Contains(cmd, "Incremental Volume") {
+			t.Errorf("unavailable field was sent: %s", cmd)
+		}
+	}
+}
+
+func TestExtraFields(t *testing.T) {
+	u := &UpdSummaryMsg{}
+	fields := map[int]string{0: "Symbol", 1: "Most Recent Trade", 2: "Message Contents", 3: "Future Field", 4: "Other Field"}
+	if err := u.UnMarshall([]string{"AAPL", "95.02", "Cbav", "x1", ""}, fields, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	if u.MostRecentTrade != 95.02 || u.MsgContents != "Cbav" {
+		t.Errorf("selected fields were not mapped: %+v", u)
+	}
+	if len(u.Extra) != 1 || u.Extra["Future Field"] != "x1" {
+		t.Errorf("Extra = %v", u.Extra)
+	}
+}
+
+func TestLegacyFieldNames(t *testing.T) {
+	u := &UpdSummaryMsg{}
+	fields := map[int]string{0: "Symbol", 1: "Last Trade Time", 2: "Market Center", 3: "Last Trade Date"}
+	if err := u.UnMarshall([]string{"AAPL", "10:15:01", "11", "03/05/2024"}, fields, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	if u.LastTime.Hour() != 10 || u.LastMktCntr != 11 || u.AskMktCenter != 0 || u.LastTrdDate.Day() != 5 {
+		t.Errorf("4.x fields were mapped to the wrong struct fields: %+v", u)
+	}
+	u.AttachDate(time.Date(2024, time.March, 6, 9, 0, 0, 0, time.UTC))
+	if want := time.Date(2024, time.March, 5, 10, 15, 1, 0, time.UTC); !u.LastTime.Equal(want) {
+		t.Errorf("LastTime = %s, want %s", u.LastTime, want)
+	}
+}
+
+func TestContentFlags(t *testing.T) {
+	f := ParseContentFlags("Cbhv")
+	if !f.IsTrade() || !f.IsLastQualifiedTrade() || f.IsExtendedTrade() || !f.IsQuote() || !f.IsBidUpdate() ||
+		f.IsAskUpdate() || !f.IsNewHigh() || !f.IsVolumeUpdate() {
+		t.Errorf("ParseContentFlags(Cbhv) = %s", f)
+	}
+	if f.String() != "Cbhv" {
+		t.Errorf("String() = %s", f)
+	}
+	if f := ParseContentFlags("a"); f.IsTrade() || !f.Has(ContentAsk) {
+		t.Errorf("ParseContentFlags(a) = %s", f)
+	}
+}
+
+func TestTradeConditions(t *testing.T) {
+	conds := ParseTradeConditions("01870F")
+	if len(conds) != 3 || conds[0].ShortName != "REGULAR" || conds[1].ShortName != "ODDLOT" || conds[2].Code != 15 {
+		t.Fatalf("ParseTradeConditions(01870F) = %+v", conds)
+	}
+	if !conds[0].UpdatesLast() || conds[1].UpdatesLast() || conds[1].UpdatesHighLow() || !conds[1].UpdatesVolume() ||
+		!conds[2].Class.Has(CondOutOfSequence) {
+		t.Errorf("unexpected classes %+v", conds)
+	}
+	u := &UpdSummaryMsg{MostRecntTradeCond: "0187"}
+	if cl := u.TradeConditionClasses(); !cl.Has(CondOddLot) || cl.Has(CondOutOfSequence) {
+		t.Errorf("TradeConditionClasses() = %b", cl)
+	}
+	// Cancels and the official close are not trades, they must not add to the volume.
+	for _, c := range ParseTradeConditions("1924") {
+		if c.UpdatesVolume() || c.UpdatesLast() {
+			t.Errorf("condition %+v updates the volume or the last price", c)
+		}
+	}
+
+	mt := &MarketTables{TradeConditions: map[int]*CodeName{200: {ID: 200, ShortName: "ODDLOT", LongName: "Odd Lot"}}}
+	tbl := NewTradeConditionTable()
+	tbl.RefreshFrom(mt)
+	if c := tbl.Parse("C801"); c[0].Code != 200 || !c[0].Class.Has(CondOddLot) || c[1].ShortName != "01" {
+		t.Errorf("refreshed Parse(C801) = %+v", c)
+	}
+	if names := mt.TradeConditionNames("C801"); len(names) != 2 || names[0] != "ODDLOT" || names[1] != "01" {
+		t.Errorf("TradeConditionNames(C801) = %q", names)
+	}
+}
+
+func TestFundamentalProtocols(t *testing.T) {
+	// The lines follow the 6.2 layout of http://www.iqfeed.net/dev/api/docs/Level1FundamentalMessage.cfm.
+	data, err := os.ReadFile("testdata/fundamental-6.2.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	equity, future := []byte(strings.TrimPrefix(lines[0], "F,")), []byte(strings.TrimPrefix(lines[1], "F,"))
+	v62 := ProtocolVersion{Major: 6, Minor: 2}
+
+	f := &FundamentalMsg{}
+	if err := f.UnMarshallProtocol(future, time.UTC, v62); err != nil {
+		t.Fatal(err)
+	}
+	if f.Symbol != "@ESM24" || f.ExchangeRoot != "ES" || f.SessionOpenTime != "18:00:00" || f.SessionCloseTime != "17:00:00" ||
+		f.BaseCurrency != "USD" || f.ContractSize != "50" || f.ContractMonths != "HMUZ" || f.MinTickSize != 0.25 ||
+		!f.FirstDeliveryDate.Equal(time.Date(2024, time.June, 21, 0, 0, 0, 0, time.UTC)) || f.FIGI != "BBG01F0B2ZX7" ||
+		f.SecuritySubType != "0" {
+		t.Errorf("6.2 future = %+v", f)
+	}
+	if !f.Has(FndFIGI) || !f.Has(FndSecuritySubType) || f.Has(FndOptionPremMult) {
+		t.Errorf("Has() is wrong for the 6.x fields, %b %b", f.Present, f.presentHigh)
+	}
+
+	// Without a protocol the layout is picked by the number of fields.
+	f = &FundamentalMsg{}
+	if err := f.UnMarshall(equity, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	if f.CompanyName != "APPLE" || f.NAICS != 334220 || f.BaseCurrency != "USD" || f.MinTickSize != 0.01 || f.FIGI != "BBG000B9XRY4" {
+		t.Errorf("6.2 equity = %+v", f)
+	}
+
+	// Older protocols stop at their own fields, a line too short for the protocol is an error.
+	f = &FundamentalMsg{}
+	if err := f.UnMarshallProtocol(future, time.UTC, ProtocolVersion{Major: 6, Minor: 0}); err != nil || f.ContractMonths != "" {
+		t.Errorf("6.0 layout read %q, %v", f.ContractMonths, err)
+	}
+	short := future[:bytes.Index(future, []byte(",18:00:00"))]
+	err = (&FundamentalMsg{}).UnMarshallProtocol(short, time.UTC, v62)
+	if perr, ok := err.(*ParseError); !ok || perr.Field != "SessionOpenTime" {
+		t.Errorf("UnMarshallProtocol of a 6.0 line as 6.2 = %v", err)
+	}
+
+	// The client parses with the negotiated protocol.
+	srv := iqfeedtest.NewServer()
+	defer srv.Close()
+	srv.Script("@ESM24", lines[1])
+	c := &IQC{Protocol: "6.2"}
+	if _, err := c.Start(context.Background(), srv.Addr); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	defer c.Close()
+	c.WatchSymbol("@ESM24")
+	for {
+		select {
+		case f := <-c.Fundamental:
+			if f.FIGI != "BBG01F0B2ZX7" {
+				t.Errorf("FIGI = %q", f.FIGI)
+			}
+			return
+		case perr := <-c.ParseErrors:
+			t.Fatalf("unexpected parse error %v", perr)
+		case <-c.System:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the fundamental message")
+		}
+	}
+}
+
+func TestDecimalPrices(t *testing.T) {
+	items := make([]string, 56)
+	items[0], items[4], items[39] = "AAPL", "134.54", "4"
+	srv := iqfeedtest.NewServer()
+	defer srv.Close()
+	srv.Script("AAPL", "F,"+strings.Join(items, ","), "R,AAPL,5,95.02,100,09:30:00,95.04,200,09:30:01,14,4,11")
+
+	c := &IQC{DecimalPrices: true}
+	if _, err := c.Start(context.Background(), srv.Addr); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	defer c.Close()
+	c.WatchSymbol("AAPL")
+
+	var gotFnd, gotReg bool
+	timeout := time.After(5 * time.Second)
+	for !(gotFnd && gotReg) {
+		select {
+		case f := <-c.Fundamental:
+			if d, ok := f.Decimal(FndFifty2WkHigh); !ok || d != (Price{Value: 1345400, Scale: 4}) {
+				t.Errorf("Decimal(FndFifty2WkHigh) = %v, %t", d, ok)
+			}
+			gotFnd = true
+		case r := <-c.Regional:
+			bid, okBid := r.Decimal(RegFieldBid)
+			ask, okAsk := r.Decimal(RegFieldAsk)
+			if !okBid || !okAsk || bid != (Price{Value: 950200, Scale: 4}) || ask != (Price{Value: 950400, Scale: 4}) {
+				t.Errorf("regional decimals = %v, %t, %v, %t", bid, okBid, ask, okAsk)
+			}
+			gotReg = true
+		case perr := <-c.ParseErrors:
+			t.Fatalf("unexpected parse error %v", perr)
+		case <-c.System:
+		case <-timeout:
+			t.Fatalf("timed out: fundamental %t, regional %t", gotFnd, gotReg)
+		}
+	}
+
+	r := &RegionalMsg{}
+	if err := r.UnMarshall([]byte("AAPL,5,95.02,100,09:30:00,95.04,200,09:30:01,14,4,11"), time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := r.Decimal(RegFieldBid); ok {
+		t.Errorf("Decimal(RegFieldBid) = %v without DecimalPrices", d)
+	}
+
+	// Prices that only parse as floats are reported with the name of the field.
+	items[4], items[5] = "134.54", "1e2"
+	f := &FundamentalMsg{DecimalPrices: true}
+	err := f.UnMarshall([]byte(strings.Join(items, ",")), time.UTC)
+	if perr, ok := err.(*ParseError); !ok || perr.Field != "Fifty2WkLow" {
+		t.Errorf("UnMarshall with an invalid low = %v", err)
+	}
 }
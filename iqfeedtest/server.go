@@ -0,0 +1,311 @@
+// Package iqfeedtest provides an in-process fake IQConnect Level 1 server so clients of the iqfeed package can be tested
+// without a running IQFeed.
+package iqfeedtest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultUpdateFields is the update fieldset a new connection starts with, it matches the IQConnect 5.x default.
+var DefaultUpdateFields = []string{
+	"Symbol", "Most Recent Trade", "Most Recent Trade Size", "Most Recent Trade TimeMS", "Most Recent Trade Market Center",
+	"Total Volume", "Bid", "Bid Size", "Ask", "Ask Size", "Open", "High", "Low", "Close", "Message Contents",
+	"Most Recent Trade Conditions",
+}
+
+// AllUpdateFields is the list returned for S,REQUEST ALL UPDATE FIELDNAMES, it matches the IQConnect 5.x list.
+var AllUpdateFields = []string{
+	"Symbol", "7 Day Yield", "Ask", "Ask Change", "Ask Market Center", "Ask Size", "Ask Time", "Available Regions",
+	"Average Maturity", "Bid", "Bid Change", "Bid Market Center", "Bid Size", "Bid Time", "Change", "Change From Open",
+	"Close", "Close Range 1", "Close Range 2", "Days to Expiration", "Decimal Precision", "Delay", "Exchange ID",
+	"Extended Trade", "Extended Trade Date", "Extended Trade Market Center", "Extended Trade Size", "Extended Trade Time",
+	"Extended Trading Change", "Extended Trading Difference", "Financial Status Indicator", "Fraction Display Code",
+	"High", "Last", "Last Date", "Last Market Center", "Last Size", "Last Time", "Low", "Market Capitalization",
+	"Market Open", "Message Contents", "Most Recent Trade", "Most Recent Trade Conditions", "Most Recent Trade Date",
+	"Most Recent Trade Market Center", "Most Recent Trade Size", "Most Recent Trade Time", "Net Asset Value",
+	"Number of Trades Today", "Open", "Open Interest", "Open Range 1", "Open Range 2", "Percent Change",
+	"Percent Off Average Volume", "Previous Day Volume", "Price-Earnings Ratio", "Range", "Restricted Code", "Settle",
+	"Settlement Date", "Spread", "Tick", "TickID", "Total Volume", "Type", "Volatility", "VWAP",
+}
+
+// Server is a fake IQConnect Level 1 port. It answers the field name, protocol and watch commands, sends the scripted
+// messages for watched symbols and can drop every connection to simulate IQConnect going away.
+type Server struct {
+	Addr         string           // The address clients should connect to.
+	TimeInterval time.Duration    // Interval between T messages, 0 disables them.
+	Now          func() time.Time // The clock used for T messages, defaults to time.Now.
+	Protocol     string           // When set every S,SET PROTOCOL is answered with this protocol, simulating an older IQConnect. See SetProtocol.
+	ln           net.Listener
+	mu           sync.Mutex
+	cond         *sync.Cond
+	conns        map[*conn]bool
+	scripts      map[string][]string
+	fields       []string
+	allFields    []string
+	commands     []string
+	accepted     int
+	wg           sync.WaitGroup
+	closed       bool
+}
+
+type conn struct {
+	net.Conn
+	mu     sync.Mutex
+	fields []string
+}
+
+func (c *conn) send(lines ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, l := range lines {
+		c.Write([]byte(l + "\r\n"))
+	}
+}
+
+// NewServer starts a server listening on a random local port, it must be closed with Close.
+func NewServer() *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("iqfeedtest: failed to listen: " + err.Error())
+	}
+	s := &Server{
+		Addr:      ln.Addr().String(),
+		ln:        ln,
+		conns:     make(map[*conn]bool),
+		scripts:   make(map[string][]string),
+		fields:    DefaultUpdateFields,
+		allFields: AllUpdateFields,
+	}
+	s.cond = sync.NewCond(&s.mu)
+	s.wg.Add(1)
+	go s.accept()
+	return s
+}
+
+// Close stops the server and closes every connection.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.ln.Close()
+	s.Disconnect()
+	s.wg.Wait()
+}
+
+// SetUpdateFields changes the fieldset new connections start with.
+func (s *Server) SetUpdateFields(fields ...string) {
+	s.mu.Lock()
+	s.fields = fields
+	s.mu.Unlock()
+}
+
+// SetProtocol changes Protocol while clients are connected, the empty protocol acknowledges every S,SET PROTOCOL as sent.
+func (s *Server) SetProtocol(protocol string) {
+	s.mu.Lock()
+	s.Protocol = protocol
+	s.mu.Unlock()
+}
+
+// SetAllUpdateFields changes the list returned for S,REQUEST ALL UPDATE FIELDNAMES.
+func (s *Server) SetAllUpdateFields(fields ...string) {
+	s.mu.Lock()
+	s.allFields = fields
+	s.mu.Unlock()
+}
+
+// Script sets the raw lines (ex: F,AAPL,... and P,AAPL,...) sent when a symbol is watched, watching a symbol without a
+// script returns a symbol not found message.
+func (s *Server) Script(symbol string, lines ...string) {
+	s.mu.Lock()
+	s.scripts[symbol] = lines
+	s.mu.Unlock()
+}
+
+// Send writes the raw lines (ex: Q,AAPL,...) to every connected client.
+func (s *Server) Send(lines ...string) {
+	s.mu.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.send(lines...)
+	}
+}
+
+// Disconnect closes every client connection while the server keeps accepting new ones, simulating a dropped connection.
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+}
+
+// Commands returns every command received so far without the trailing line break.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// WaitCommand waits until the command has been received count times in total, it returns false on timeout.
+func (s *Server) WaitCommand(cmd string, count int, timeout time.Duration) bool {
+	return s.wait(timeout, func() bool {
+		n := 0
+		for _, c := range s.commands {
+			if c == cmd {
+				n++
+			}
+		}
+		return n >= count
+	})
+}
+
+// WaitConnections waits until the server has accepted n connections in total, it returns false on timeout.
+func (s *Server) WaitConnections(n int, timeout time.Duration) bool {
+	return s.wait(timeout, func() bool { return s.accepted >= n })
+}
+
+func (s *Server) wait(timeout time.Duration, done func() bool) bool {
+	timer := time.AfterFunc(timeout, func() {
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	})
+	defer timer.Stop()
+	deadline := time.Now().Add(timeout)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for !done() {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		s.cond.Wait()
+	}
+	return true
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			nc.Close()
+			return
+		}
+		c := &conn{Conn: nc, fields: s.fields}
+		s.conns[c] = true
+		s.accepted++
+		s.cond.Broadcast()
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.serve(c)
+	}
+}
+
+func (s *Server) serve(c *conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+	stop := make(chan struct{})
+	defer close(stop)
+	if s.TimeInterval > 0 {
+		go s.timestamps(c, stop)
+	}
+	r := bufio.NewReader(c)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		s.mu.Lock()
+		s.commands = append(s.commands, cmd)
+		s.cond.Broadcast()
+		s.mu.Unlock()
+		s.handle(c, cmd)
+	}
+}
+
+func (s *Server) timestamps(c *conn, stop chan struct{}) {
+	t := time.NewTicker(s.TimeInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			c.send(s.timeMsg())
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Server) timeMsg() string {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	return "T," + now().Format("20060102 15:04:05")
+}
+
+// Handle answers a single command the way IQConnect would.
+func (s *Server) handle(c *conn, cmd string) {
+	switch {
+	case cmd == "S,REQUEST CURRENT UPDATE FIELDNAMES":
+		c.send("S,CURRENT UPDATE FIELDNAMES," + strings.Join(c.fields, ","))
+	case cmd == "S,REQUEST ALL UPDATE FIELDNAMES":
+		s.mu.Lock()
+		all := s.allFields
+		s.mu.Unlock()
+		c.send("S,UPDATE FIELDNAMES," + strings.Join(all, ","))
+	case strings.HasPrefix(cmd, "S,SELECT UPDATE FIELDS,"):
+		// Symbol is always the first field whether it was selected or not.
+		fields := []string{"Symbol"}
+		for _, f := range strings.Split(strings.TrimPrefix(cmd, "S,SELECT UPDATE FIELDS,"), ",") {
+			if f != "" && f != "Symbol" {
+				fields = append(fields, f)
+			}
+		}
+		c.fields = fields
+		c.send("S,CURRENT UPDATE FIELDNAMES," + strings.Join(fields, ","))
+	case strings.HasPrefix(cmd, "S,SET PROTOCOL,"):
+		protocol := strings.TrimPrefix(cmd, "S,SET PROTOCOL,")
+		s.mu.Lock()
+		if s.Protocol != "" {
+			protocol = s.Protocol
+		}
+		s.mu.Unlock()
+		c.send("S,CURRENT PROTOCOL," + protocol)
+	case strings.HasPrefix(cmd, "S,SET CLIENT NAME,"):
+		c.send("S,CURRENT CLIENT NAME," + strings.TrimPrefix(cmd, "S,SET CLIENT NAME,"))
+	case cmd == "S,REQUEST STATS":
+		c.send("S,STATS,127.0.0.1,60002,1300,0,1,0,0,0,Jan 02 9:30AM,Jan 02 9:31AM,Connected,6.2.0.25,TEST,0.00,0.00,0.00,0.00,0.00,0.00")
+	case cmd == "T":
+		c.send(s.timeMsg())
+	case len(cmd) > 1 && (cmd[0] == 'w' || cmd[0] == 't'):
+		sym := cmd[1:]
+		s.mu.Lock()
+		script, ok := s.scripts[sym]
+		s.mu.Unlock()
+		if !ok {
+			c.send("n," + sym)
+			return
+		}
+		c.send(script...)
+	}
+}
@@ -0,0 +1,248 @@
+package iqfeed
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// L2Client provides the client for the IQFeed Level 2 port which streams market depth. Every depth message of a watched
+// symbol is applied to its order book before it is sent on Depth, so the book returned by Book always reflects the messages received.
+type L2Client struct {
+	Depth        chan *DepthMsg       // Receives every summary (Z) and update (2) message.
+	MarketMakers chan *MarketMakerMsg // Receives responses to RequestMarketMaker.
+	System       chan *SystemMessage
+	Errors       chan *ErrorMsg
+	Time         chan *TimeMsg
+	ParseErrors  chan *ParseError // Receives malformed messages that were dropped, buffered and dropped when full so it may be ignored.
+	TimeZone     string
+	TimeLoc      *time.Location
+	Conn         net.Conn
+	mu           sync.RWMutex
+	books        map[string]*OrderBook // The books of the watched symbols, see WatchDepth.
+	mmNames      map[string]string
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// Start connects to the Level 2 port (defaults to localhost:9200) and starts reading depth, cancelling ctx has the same
+// effect as calling Close.
+func (l *L2Client) Start(ctx context.Context, connectString string) (*L2Client, error) {
+	var err error
+	l.TimeLoc, err = loadTimeLoc(&l.TimeZone, l.TimeLoc)
+	if err != nil {
+		return nil, err
+	}
+	if connectString == "" {
+		connectString = "localhost:9200"
+	}
+	l.Conn, err = net.Dial("tcp", connectString)
+	if err != nil {
+		return nil, &DialError{Addr: connectString, Err: err}
+	}
+	l.books = make(map[string]*OrderBook)
+	l.mmNames = make(map[string]string)
+	l.Depth = make(chan *DepthMsg)
+	l.MarketMakers = make(chan *MarketMakerMsg)
+	l.System = make(chan *SystemMessage)
+	l.Errors = make(chan *ErrorMsg)
+	l.Time = make(chan *TimeMsg)
+	l.ParseErrors = make(chan *ParseError, 100)
+	l.ctx, l.cancel = context.WithCancel(ctx)
+	l.wg.Add(2)
+	go l.read()
+	go func() {
+		defer l.wg.Done()
+		<-l.ctx.Done()
+		l.Conn.Close()
+	}()
+	return l, nil
+}
+
+// Close closes the connection to the Level 2 port and returns once all goroutines have exited and channels are closed.
+func (l *L2Client) Close() error {
+	if l.cancel == nil {
+		return nil
+	}
+	l.cancel()
+	l.wg.Wait()
+	return nil
+}
+
+// Read function does as expected and reads data from the network stream until the client is closed.
+func (l *L2Client) read() {
+	defer l.wg.Done()
+	defer func() {
+		close(l.Depth)
+		close(l.MarketMakers)
+		close(l.System)
+		close(l.Errors)
+		close(l.Time)
+		close(l.ParseErrors)
+	}()
+	r := bufio.NewReader(l.Conn)
+	for {
+		line, isPrefix, err := r.ReadLine()
+		if err != nil {
+			if l.ctx.Err() == nil {
+				log.Printf("Level 2 connection closed: %s", err)
+				l.cancel()
+			}
+			return
+		}
+		if isPrefix {
+			log.Println("buffer size to small")
+			continue
+		}
+		l.processReceiver(line)
+	}
+}
+
+// ProcessReceiver interprets the data received on the Level 2 port and processes it in sub functions.
+func (l *L2Client) processReceiver(d []byte) {
+	if len(d) < 3 {
+		return
+	}
+	data := d[2:]
+	switch d[0] {
+	case 'Z', '2': // Summary or update message for a market maker / price level.
+		m := &DepthMsg{}
+		if err := m.UnMarshall(string(d[0]), data, l.TimeLoc); err != nil {
+			reportParseError(l.ParseErrors, err)
+			return
+		}
+		l.mu.Lock()
+		m.MarketMakerTag = l.mmNames[m.MMID]
+		b := l.books[m.Symbol]
+		l.mu.Unlock()
+		// Messages still in flight after UnwatchDepth have no book to apply to.
+		if b != nil {
+			b.Apply(m)
+		}
+		select {
+		case l.Depth <- m:
+		case <-l.ctx.Done():
+		}
+	case 'M': // Market maker name.
+		m := &MarketMakerMsg{}
+		if err := m.UnMarshall(data); err != nil {
+			reportParseError(l.ParseErrors, err)
+			return
+		}
+		l.mu.Lock()
+		l.mmNames[m.MMID] = m.Name
+		l.mu.Unlock()
+		select {
+		case l.MarketMakers <- m:
+		case <-l.ctx.Done():
+		}
+	case 'T':
+		t := &TimeMsg{}
+		if err := t.UnMarshall(data, l.TimeLoc); err != nil {
+			reportParseError(l.ParseErrors, err)
+			return
+		}
+		select {
+		case l.Time <- t:
+		case <-l.ctx.Done():
+		}
+	case 'S':
+		s := &SystemMessage{}
+		if err := s.UnMarshall(data, l.TimeLoc); err != nil {
+			reportParseError(l.ParseErrors, err)
+			return
+		}
+		select {
+		case l.System <- s:
+		case <-l.ctx.Done():
+		}
+	case 'n', 'E': // Symbol not found or error text.
+		e := &ErrorMsg{}
+		if d[0] == 'n' {
+			e.UnMarshall(true, data, 404)
+		} else {
+			e.UnMarshall(false, data, 500)
+		}
+		select {
+		case l.Errors <- e:
+		case <-l.ctx.Done():
+		}
+	default:
+		log.Printf("Unknown Level 2 message type: %#v", d[0])
+	}
+}
+
+// Book returns the order book for the symbol, it is only available while the symbol is watched with WatchDepth.
+func (l *L2Client) Book(symbol string) (*OrderBook, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	b, ok := l.books[symbol]
+	return b, ok
+}
+
+// MarketMakerName returns the name of a market maker previously received from RequestMarketMaker.
+func (l *L2Client) MarketMakerName(mmid string) (string, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	name, ok := l.mmNames[mmid]
+	return name, ok
+}
+
+// Write sends the raw command to the Level 2 port.
+func (l *L2Client) Write(data string) {
+	l.write(data)
+}
+
+// write sends the raw command to the Level 2 port and returns any error that occurred on the connection.
+func (l *L2Client) write(data string) error {
+	_, err := l.Conn.Write([]byte(data))
+	return err
+}
+
+// WatchDepth begins watching the market depth for a symbol with an empty order book, the current depth is delivered as
+// summary messages followed by updates. A new book is dropped again when the command could not be sent.
+func (l *L2Client) WatchDepth(symbol string) error {
+	l.mu.Lock()
+	b, ok := l.books[symbol]
+	if ok {
+		b.Reset()
+	} else {
+		l.books[symbol] = NewOrderBook(symbol)
+	}
+	l.mu.Unlock()
+	err := l.write("w" + symbol + "\r\n")
+	if err != nil && !ok {
+		l.mu.Lock()
+		delete(l.books, symbol)
+		l.mu.Unlock()
+	}
+	return err
+}
+
+// UnwatchDepth stops watching the market depth for a symbol and drops its order book, the book is dropped even when the
+// command could not be sent.
+func (l *L2Client) UnwatchDepth(symbol string) error {
+	l.mu.Lock()
+	delete(l.books, symbol)
+	l.mu.Unlock()
+	return l.write("r" + symbol + "\r\n")
+}
+
+// RequestMarketMaker requests the name of a market maker, the response is delivered on MarketMakers.
+func (l *L2Client) RequestMarketMaker(mmid string) {
+	l.Write("m" + mmid + "\r\n")
+}
+
+// SetProtocol Changes the current connection's protocol (ex: 5.2).
+func (l *L2Client) SetProtocol(protocol string) {
+	l.Write("S,SET PROTOCOL," + protocol + "\r\n")
+}
+
+// RequestTime Requests a Time Stamp message be sent.
+func (l *L2Client) RequestTime() {
+	l.Write("T\r\n")
+}
@@ -0,0 +1,355 @@
+package iqfeed
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const endMsg = "!ENDMSG!"
+
+// LookupClient provides the client for the IQFeed lookup port which serves historical data and the various lookup tables.
+// Every request is tagged with a request ID so multiple requests can be in flight on the same connection, the responses are
+// routed back to the caller until the terminating !ENDMSG! line is received.
+// Field definitions are available here: http://www.iqfeed.net/dev/api/docs/HistoricalviaTCPIP.cfm.
+type LookupClient struct {
+	TimeZone string         // Defaults to America/New_York, ignored when TimeLoc is set (ex: copied from IQC.TimeLoc).
+	TimeLoc  *time.Location // The location all lookup timestamps are parsed in.
+	Protocol string         // Negotiated by Start when set (ex: 6.2), 6.x responses are parsed without their message identifiers.
+	// HandshakeTimeout is how long NegotiateProtocol waits for the server to acknowledge the protocol, defaults to 5 seconds.
+	HandshakeTimeout time.Duration
+	Conn             net.Conn
+	mu               sync.Mutex
+	version          ProtocolVersion       // The protocol acknowledged by the server.
+	protoAck         chan string           // Receives the S,CURRENT PROTOCOL reply while NegotiateProtocol waits.
+	pending          map[string]*lookupReq // Requests waiting for data keyed by request ID.
+	nextID           int
+	tables           *MarketTables // Cached by MarketTables.
+	ctx              context.Context
+	cancel           context.CancelFunc
+	wg               sync.WaitGroup
+}
+
+// lookupReq is a single in-flight request on the lookup port. The rows are queued rather than handed over so a slow
+// caller never holds up the reader, and with it the requests of every other caller.
+type lookupReq struct {
+	mu     sync.Mutex
+	rows   [][]string    // Data rows with the request ID removed, waiting for the caller.
+	done   bool          // Set once !ENDMSG! is received or the connection is closed.
+	err    error         // Set by the reader when the server returned an error.
+	notify chan struct{} // Signalled when rows are queued or the request is done.
+}
+
+func newLookupReq() *lookupReq {
+	return &lookupReq{notify: make(chan struct{}, 1)}
+}
+
+// Push queues a data row for the caller.
+func (r *lookupReq) push(row []string) {
+	r.mu.Lock()
+	r.rows = append(r.rows, row)
+	r.mu.Unlock()
+	r.signal()
+}
+
+// Fail records the error returned to the caller, the first error is kept.
+func (r *lookupReq) fail(err error) {
+	r.mu.Lock()
+	if r.err == nil {
+		r.err = err
+	}
+	r.mu.Unlock()
+}
+
+// Complete marks the request done, err is recorded when it is not nil.
+func (r *lookupReq) complete(err error) {
+	r.mu.Lock()
+	if r.err == nil {
+		r.err = err
+	}
+	r.done = true
+	r.mu.Unlock()
+	r.signal()
+}
+
+// Take returns the rows queued since the last call and whether the request is done.
+func (r *lookupReq) take() ([][]string, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rows := r.rows
+	r.rows = nil
+	return rows, r.done, r.err
+}
+
+func (r *lookupReq) signal() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+		// The caller has not woken up for the previous signal yet, it takes every queued row at once.
+	}
+}
+
+// Start connects to the lookup port (defaults to localhost:9100) and starts reading responses, when Protocol is set Start
+// only returns once the server has acknowledged it. Cancelling ctx has the same effect as calling Close.
+func (l *LookupClient) Start(ctx context.Context, connectString string) (*LookupClient, error) {
+	var err error
+	l.TimeLoc, err = loadTimeLoc(&l.TimeZone, l.TimeLoc)
+	if err != nil {
+		return nil, err
+	}
+	if connectString == "" {
+		connectString = "localhost:9100"
+	}
+	l.Conn, err = net.Dial("tcp", connectString)
+	if err != nil {
+		return nil, &DialError{Addr: connectString, Err: err}
+	}
+	l.pending = make(map[string]*lookupReq)
+	l.ctx, l.cancel = context.WithCancel(ctx)
+	l.wg.Add(2)
+	go l.read()
+	go func() {
+		defer l.wg.Done()
+		<-l.ctx.Done()
+		l.Conn.Close()
+	}()
+	if l.Protocol != "" {
+		if _, err := l.NegotiateProtocol(ctx, l.Protocol); err != nil {
+			l.Close()
+			return nil, &HandshakeError{Command: "S,SET PROTOCOL," + l.Protocol, Err: err}
+		}
+	}
+	return l, nil
+}
+
+// NegotiateProtocol sets the protocol of the lookup connection and waits for the server to acknowledge it, a *ProtocolError
+// is returned when the server replies with a different protocol.
+func (l *LookupClient) NegotiateProtocol(ctx context.Context, protocol string) (ProtocolVersion, error) {
+	want, err := ParseProtocolVersion(protocol)
+	if err != nil {
+		return ProtocolVersion{}, err
+	}
+	if l.ctx == nil {
+		return ProtocolVersion{}, &LookupError{Message: "lookup client is not started"}
+	}
+	cmd := "S,SET PROTOCOL," + want.String()
+	ack := make(chan string, 1)
+	l.mu.Lock()
+	l.protoAck = ack
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.protoAck = nil
+		l.mu.Unlock()
+	}()
+	if _, err := l.Conn.Write([]byte(cmd + "\r\n")); err != nil {
+		return ProtocolVersion{}, err
+	}
+	timeout := l.HandshakeTimeout
+	if timeout <= 0 {
+		timeout = defaultHandshakeTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case current := <-ack:
+		got, err := ParseProtocolVersion(current)
+		if err != nil || got != want {
+			return got, &ProtocolError{Requested: want.String(), Current: current}
+		}
+		return got, nil
+	case <-timer.C:
+		return ProtocolVersion{}, &ReplyError{Command: cmd, Message: "no reply after " + timeout.String()}
+	case <-ctx.Done():
+		return ProtocolVersion{}, ctx.Err()
+	case <-l.ctx.Done():
+		return ProtocolVersion{}, &LookupError{Message: "lookup client is closed"}
+	}
+}
+
+// ProtocolVersion returns the protocol last acknowledged by the server, it is the zero version until a protocol has been set.
+func (l *LookupClient) ProtocolVersion() ProtocolVersion {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.version
+}
+
+// Close closes the connection to the lookup port, failing every outstanding request, and returns once all goroutines have exited.
+func (l *LookupClient) Close() error {
+	if l.cancel == nil {
+		return nil
+	}
+	l.cancel()
+	l.wg.Wait()
+	return nil
+}
+
+// Read dispatches every line received to the request it belongs to.
+func (l *LookupClient) read() {
+	defer l.wg.Done()
+	r := bufio.NewReader(l.Conn)
+	var err error
+	for {
+		var line string
+		line, err = r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		l.processLine(strings.TrimRight(line, "\r\n"))
+	}
+	if l.ctx.Err() == nil {
+		log.Printf("Lookup connection closed: %s", err)
+		l.cancel()
+	}
+	// Fail everything that is still waiting so callers do not block forever.
+	l.mu.Lock()
+	for id, req := range l.pending {
+		req.complete(&LookupError{RequestID: id, Message: "connection closed"})
+		delete(l.pending, id)
+	}
+	l.mu.Unlock()
+}
+
+// ProcessLine routes a single response line, lines are in the format [RequestID],[Data...] and end with [RequestID],!ENDMSG!.
+// Protocol 6.0 and newer add a message identifier after the request ID (ex: LH for history) which is removed from the data.
+func (l *LookupClient) processLine(line string) {
+	items := strings.Split(strings.TrimSuffix(line, ","), ",")
+	if len(items) > 2 && items[0] == "S" && items[1] == string(SysCurrentProtocol) {
+		l.setVersion(items[2])
+		return
+	}
+	l.mu.Lock()
+	req, ok := l.pending[items[0]]
+	v := l.version
+	l.mu.Unlock()
+	if !ok {
+		// The request was cancelled by its caller, or the line does not belong to a request.
+		return
+	}
+	data := items[1:]
+	if v.AtLeast(6, 0) && len(data) > 0 && isLookupMsgID(data[0]) {
+		data = data[1:]
+	}
+	switch {
+	case len(data) == 0:
+		// Blank rows carry no data.
+	case data[0] == endMsg:
+		l.finish(items[0])
+	case data[0] == "E":
+		// Errors are followed by !ENDMSG! so we only record it here, having no data is not an error to the caller.
+		if len(data) > 1 && data[1] != "!NO_DATA!" {
+			req.fail(&LookupError{RequestID: items[0], Message: strings.Join(data[1:], ",")})
+		}
+	default:
+		req.push(data)
+	}
+}
+
+// SetVersion records the protocol acknowledged by the server and hands it to a waiting NegotiateProtocol.
+func (l *LookupClient) setVersion(protocol string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if v, err := ParseProtocolVersion(protocol); err == nil {
+		l.version = v
+	}
+	if l.protoAck != nil {
+		l.protoAck <- protocol
+		l.protoAck = nil
+	}
+}
+
+// isLookupMsgID reports whether s is a 6.x lookup message identifier, an L followed by an upper case letter (ex: LH, LS, LC).
+func isLookupMsgID(s string) bool {
+	return len(s) == 2 && s[0] == 'L' && s[1] >= 'A' && s[1] <= 'Z'
+}
+
+// Finish completes the request and removes it from pending.
+func (l *LookupClient) finish(id string) {
+	l.mu.Lock()
+	req, ok := l.pending[id]
+	delete(l.pending, id)
+	l.mu.Unlock()
+	if ok {
+		req.complete(nil)
+	}
+}
+
+// Abandon removes a request whose caller has given up, the rows still sent for it are dropped by the reader.
+func (l *LookupClient) abandon(id string) {
+	l.mu.Lock()
+	delete(l.pending, id)
+	l.mu.Unlock()
+}
+
+// Request sends the command built for a new request ID and calls row for every data row received until the request is
+// complete, the first error returned by row or the server is returned.
+func (l *LookupClient) request(ctx context.Context, cmd func(id string) string, row func(items []string) error) error {
+	if l.ctx == nil {
+		return &LookupError{Message: "lookup client is not started"}
+	}
+	l.mu.Lock()
+	if l.ctx.Err() != nil {
+		l.mu.Unlock()
+		return &LookupError{Message: "lookup client is closed"}
+	}
+	l.nextID++
+	id := "L" + strconv.Itoa(l.nextID)
+	req := newLookupReq()
+	l.pending[id] = req
+	l.mu.Unlock()
+
+	if _, err := l.Conn.Write([]byte(cmd(id))); err != nil {
+		l.abandon(id)
+		return &LookupError{RequestID: id, Message: err.Error()}
+	}
+	var rowErr error
+	for {
+		rows, done, err := req.take()
+		for _, items := range rows {
+			if rowErr == nil {
+				rowErr = row(items)
+			}
+		}
+		if done {
+			if rowErr != nil {
+				return rowErr
+			}
+			return err
+		}
+		select {
+		case <-req.notify:
+		case <-ctx.Done():
+			l.abandon(id)
+			return ctx.Err()
+		}
+	}
+}
+
+// optInt formats n as a request parameter, zero values are sent blank so the server applies its default.
+func optInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// optTime formats t in the CCYYMMDD HHmmSS request layout, zero times are sent blank.
+func optTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format("20060102 150405")
+}
+
+// boolFlag formats b as 1 or 0.
+func boolFlag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
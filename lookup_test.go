@@ -0,0 +1,316 @@
+package iqfeed
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var lookupIDPattern = regexp.MustCompile(`^L[0-9]+$`)
+
+// startLookup starts a LookupClient against a minimal lookup port. Every command is sent on the returned channel and
+// answered with the lines returned by reply, "{id}" in them is replaced by the request ID found in the command.
+func startLookup(t *testing.T, protocol string, reply func(cmd, id string) []string) (*LookupClient, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmds := make(chan string, 16)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimRight(line, "\r\n")
+			if v, ok := strings.CutPrefix(cmd, "S,SET PROTOCOL,"); ok {
+				conn.Write([]byte("S,CURRENT PROTOCOL," + v + "\r\n"))
+				continue
+			}
+			var id string
+			for _, item := range strings.Split(cmd, ",") {
+				if lookupIDPattern.MatchString(item) {
+					id = item
+				}
+			}
+			cmds <- cmd
+			for _, l := range reply(cmd, id) {
+				conn.Write([]byte(strings.ReplaceAll(l, "{id}", id) + "\r\n"))
+			}
+		}
+	}()
+	l := &LookupClient{TimeZone: "America/New_York", Protocol: protocol}
+	if _, err := l.Start(context.Background(), ln.Addr().String()); err != nil {
+		ln.Close()
+		t.Fatalf("Start: %s", err)
+	}
+	t.Cleanup(func() {
+		l.Close()
+		ln.Close()
+	})
+	return l, cmds
+}
+
+func TestLookupHistory(t *testing.T) {
+	l, cmds := startLookup(t, "6.2", func(cmd, id string) []string {
+		switch {
+		case strings.HasPrefix(cmd, "HTX,"):
+			return []string{
+				"{id},LH,2024-03-05 09:30:00.123456,170.1200,100,12345,170.1100,170.1300,1001,C,11,3D87,",
+				"{id},LH,2024-03-05 09:30:01.000001,170.1500,200,12545,170.1400,170.1600,1002,E,19,01,",
+				"{id},!ENDMSG!,",
+			}
+		case strings.HasPrefix(cmd, "HIX,"):
+			return []string{"{id},LH,2024-03-05 09:31:00,170.5000,170.0000,170.1000,170.2500,12545,1200,14,", "{id},!ENDMSG!,"}
+		case strings.HasPrefix(cmd, "HDX,"):
+			return []string{"{id},LH,2024-03-04,175.1000,169.5000,170.0000,174.0000,51234567,0,", "{id},!ENDMSG!,"}
+		}
+		return []string{"{id},E,Unexpected command.,", "{id},!ENDMSG!,"}
+	})
+	ctx := context.Background()
+
+	ticks, err := l.Ticks(ctx, &TickRequest{Symbol: "AAPL", MaxDatapoints: 2})
+	if err != nil {
+		t.Fatalf("Ticks: %s", err)
+	}
+	if cmd := <-cmds; cmd != "HTX,AAPL,2,0,L1," {
+		t.Errorf("command = %q", cmd)
+	}
+	want := time.Date(2024, time.March, 5, 9, 30, 0, 123456000, l.TimeLoc)
+	if len(ticks) != 2 || !ticks[0].TimeStamp.Equal(want) || ticks[0].Last != 170.12 || ticks[0].LastSize != 100 ||
+		ticks[0].TotalVol != 12345 || ticks[0].TickID != 1001 || ticks[0].TradeMktCenter != 11 || ticks[0].TradeConditions != "3D87" ||
+		ticks[1].BasisForLast != "E" {
+		t.Errorf("ticks = %+v", ticks)
+	}
+
+	bars, err := l.Bars(ctx, &IntervalRequest{Symbol: "AAPL", Interval: 60, OldestFirst: true})
+	if err != nil {
+		t.Fatalf("Bars: %s", err)
+	}
+	if cmd := <-cmds; cmd != "HIX,AAPL,60,,1,L2,,s,0" {
+		t.Errorf("command = %q", cmd)
+	}
+	if len(bars) != 1 || bars[0].High != 170.5 || bars[0].Low != 170 || bars[0].Open != 170.1 || bars[0].Close != 170.25 ||
+		bars[0].TotalVol != 12545 || bars[0].PeriodVol != 1200 || bars[0].NumTrades != 14 {
+		t.Errorf("bars = %+v", bars)
+	}
+
+	daily, err := l.DailyBars(ctx, &DailyRequest{Symbol: "AAPL", MaxDatapoints: 1})
+	if err != nil {
+		t.Fatalf("DailyBars: %s", err)
+	}
+	if cmd := <-cmds; cmd != "HDX,AAPL,1,0,L3," {
+		t.Errorf("command = %q", cmd)
+	}
+	if len(daily) != 1 || !daily[0].TimeStamp.Equal(time.Date(2024, time.March, 4, 0, 0, 0, 0, l.TimeLoc)) ||
+		daily[0].Close != 174 || daily[0].PeriodVol != 51234567 {
+		t.Errorf("daily bars = %+v", daily)
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	l, _ := startLookup(t, "", func(cmd, id string) []string {
+		if strings.HasPrefix(cmd, "HTX,NOPE,") {
+			return []string{"{id},E,Invalid symbol.,", "{id},!ENDMSG!,"}
+		}
+		return []string{"{id},E,!NO_DATA!,", "{id},!ENDMSG!,"}
+	})
+	ctx := context.Background()
+
+	_, err := l.Ticks(ctx, &TickRequest{Symbol: "NOPE"})
+	var lerr *LookupError
+	if !errors.As(err, &lerr) || lerr.RequestID != "L1" || lerr.Message != "Invalid symbol." {
+		t.Errorf("Ticks error = %v", err)
+	}
+	// No data is an empty result, not an error.
+	ticks, err := l.Ticks(ctx, &TickRequest{Symbol: "AAPL"})
+	if err != nil || len(ticks) != 0 {
+		t.Errorf("Ticks = %v, %v", ticks, err)
+	}
+}
+
+func TestLookupRouting(t *testing.T) {
+	// The first request is only answered together with the second one, with the rows of both requests interleaved.
+	l, cmds := startLookup(t, "", func(cmd, id string) []string {
+		if id != "L2" {
+			return nil
+		}
+		return []string{
+			"L2,2024-03-05 09:30:00,5.5000,10,10,5.4900,5.5100,7,C,11,01,",
+			"L1,2024-03-05 09:30:00,170.1200,100,100,170.1100,170.1300,1,C,11,01,",
+			"L1,2024-03-05 09:30:01,170.1300,100,200,170.1100,170.1300,2,C,11,01,",
+			"L1,!ENDMSG!,",
+			"L2,2024-03-05 09:30:01,5.5100,10,20,5.5000,5.5200,8,C,11,01,",
+			"L2,!ENDMSG!,",
+		}
+	})
+	ctx := context.Background()
+	type result struct {
+		ticks []*Tick
+		err   error
+	}
+	first := make(chan result, 1)
+	go func() {
+		ticks, err := l.Ticks(ctx, &TickRequest{Symbol: "AAPL"})
+		first <- result{ticks, err}
+	}()
+	<-cmds
+	ticks, err := l.Ticks(ctx, &TickRequest{Symbol: "F"})
+	if err != nil {
+		t.Fatalf("Ticks: %s", err)
+	}
+	if len(ticks) != 2 || ticks[0].TickID != 7 || ticks[1].TickID != 8 {
+		t.Errorf("second request ticks = %+v", ticks)
+	}
+	select {
+	case r := <-first:
+		if r.err != nil || len(r.ticks) != 2 || r.ticks[0].TickID != 1 || r.ticks[1].TickID != 2 {
+			t.Errorf("first request = %+v, %v", r.ticks, r.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the first request did not complete on its !ENDMSG!")
+	}
+}
+
+func TestLookupClosed(t *testing.T) {
+	l, cmds := startLookup(t, "", func(cmd, id string) []string { return nil })
+	done := make(chan error, 1)
+	go func() {
+		_, err := l.Ticks(context.Background(), &TickRequest{Symbol: "AAPL"})
+		done <- err
+	}()
+	<-cmds
+	l.Close()
+	select {
+	case err := <-done:
+		var lerr *LookupError
+		if !errors.As(err, &lerr) || lerr.RequestID != "L1" {
+			t.Errorf("error = %v, want the request to fail", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("an outstanding request was not failed by Close")
+	}
+}
+
+func TestLookupHandshakeTimeout(t *testing.T) {
+	// The listener accepts the connection but never answers S,SET PROTOCOL.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+	}()
+	l := &LookupClient{Protocol: "6.2", HandshakeTimeout: 50 * time.Millisecond}
+	start := time.Now()
+	_, err = l.Start(context.Background(), ln.Addr().String())
+	var herr *HandshakeError
+	if !errors.As(err, &herr) {
+		t.Fatalf("Start = %v, want a *HandshakeError", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Start gave up after %s, want HandshakeTimeout", elapsed)
+	}
+}
+
+func TestLookupCancel(t *testing.T) {
+	// The first request is never answered until the second one is sent, its rows then arrive after it was cancelled.
+	l, cmds := startLookup(t, "", func(cmd, id string) []string {
+		if id != "L2" {
+			return nil
+		}
+		return []string{
+			"L1,2024-03-05 09:30:00,170.1200,100,100,170.1100,170.1300,1,C,11,01,",
+			"L1,!ENDMSG!,",
+			"L2,2024-03-05 09:30:00,5.5000,10,10,5.4900,5.5100,7,C,11,01,",
+			"L2,!ENDMSG!,",
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := l.Ticks(ctx, &TickRequest{Symbol: "AAPL"})
+		done <- err
+	}()
+	<-cmds
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request did not return when its context was cancelled")
+	}
+	l.mu.Lock()
+	pending := len(l.pending)
+	l.mu.Unlock()
+	if pending != 0 {
+		t.Errorf("%d requests pending after the cancel, want 0", pending)
+	}
+	ticks, err := l.Ticks(context.Background(), &TickRequest{Symbol: "F"})
+	if err != nil || len(ticks) != 1 || ticks[0].TickID != 7 {
+		t.Errorf("Ticks = %+v, %v", ticks, err)
+	}
+}
+
+func TestLookupSlowCaller(t *testing.T) {
+	rows := make([]string, 0, 201)
+	for i := 0; i < 200; i++ {
+		rows = append(rows, "{id},2024-03-05 09:30:00,170.1200,100,100,170.1100,170.1300,1,C,11,01,")
+	}
+	rows = append(rows, "{id},!ENDMSG!,")
+	l, cmds := startLookup(t, "", func(cmd, id string) []string {
+		if strings.HasPrefix(cmd, "HTX,AAPL,") {
+			return rows
+		}
+		return []string{"{id},2024-03-05 09:30:00,5.5000,10,10,5.4900,5.5100,7,C,11,01,", "{id},!ENDMSG!,"}
+	})
+	// Nothing reads out until the second request is complete.
+	out := make(chan *Tick)
+	first := make(chan error, 1)
+	go func() {
+		first <- l.StreamTicks(context.Background(), &TickRequest{Symbol: "AAPL"}, out)
+	}()
+	<-cmds
+	second := make(chan error, 1)
+	go func() {
+		_, err := l.Ticks(context.Background(), &TickRequest{Symbol: "F"})
+		second <- err
+	}()
+	select {
+	case err := <-second:
+		if err != nil {
+			t.Errorf("Ticks: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a slow caller blocked the other requests")
+	}
+	n := 0
+	for n < 200 {
+		<-out
+		n++
+	}
+	if err := <-first; err != nil {
+		t.Errorf("StreamTicks: %s", err)
+	}
+}
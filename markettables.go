@@ -0,0 +1,208 @@
+package iqfeed
+
+import (
+	"context"
+	"strings"
+)
+
+// ListedMarket is a row of the listed markets table (SLM), see: http://www.iqfeed.net/dev/api/docs/ListedMarkets.cfm.
+type ListedMarket struct {
+	ID        int
+	ShortName string // ex: NASDAQ
+	LongName  string // ex: Nasdaq Global Select Market
+	GroupID   int    // The ID of the market group the market belongs to
+	GroupName string // The short name of the market group
+}
+
+// CodeName is a row of the security types (SST) and trade conditions (STC) tables.
+type CodeName struct {
+	ID        int
+	ShortName string
+	LongName  string
+}
+
+// IndustryCode is a row of the SIC (SSC) and NAICS (SNC) code tables.
+type IndustryCode struct {
+	Code        int
+	Description string
+}
+
+// ListedMarkets returns the listed markets table.
+func (l *LookupClient) ListedMarkets(ctx context.Context) ([]*ListedMarket, error) {
+	var rows []*ListedMarket
+	err := l.request(ctx, tableCommand("SLM"), func(items []string) error {
+		p := newFieldParser("ListedMarket", strings.Join(items, ","), items, nil)
+		rows = append(rows, &ListedMarket{
+			ID:        p.int(0, "ID"),
+			ShortName: p.str(1, "ShortName"),
+			LongName:  p.str(2, "LongName"),
+			GroupID:   p.int(3, "GroupID"),
+			GroupName: p.str(4, "GroupName"),
+		})
+		return p.error()
+	})
+	return rows, err
+}
+
+// SecurityTypes returns the security types table.
+func (l *LookupClient) SecurityTypes(ctx context.Context) ([]*CodeName, error) {
+	return l.codeNames(ctx, "SST")
+}
+
+// TradeConditions returns the trade conditions table.
+func (l *LookupClient) TradeConditions(ctx context.Context) ([]*CodeName, error) {
+	return l.codeNames(ctx, "STC")
+}
+
+// SICCodes returns the SIC codes table.
+func (l *LookupClient) SICCodes(ctx context.Context) ([]*IndustryCode, error) {
+	return l.industryCodes(ctx, "SSC")
+}
+
+// NAICSCodes returns the NAICS codes table.
+func (l *LookupClient) NAICSCodes(ctx context.Context) ([]*IndustryCode, error) {
+	return l.industryCodes(ctx, "SNC")
+}
+
+func (l *LookupClient) codeNames(ctx context.Context, cmd string) ([]*CodeName, error) {
+	var rows []*CodeName
+	err := l.request(ctx, tableCommand(cmd), func(items []string) error {
+		p := newFieldParser("CodeName", strings.Join(items, ","), items, nil)
+		rows = append(rows, &CodeName{ID: p.int(0, "ID"), ShortName: p.str(1, "ShortName"), LongName: p.rest(2, "LongName")})
+		return p.error()
+	})
+	return rows, err
+}
+
+func (l *LookupClient) industryCodes(ctx context.Context, cmd string) ([]*IndustryCode, error) {
+	var rows []*IndustryCode
+	err := l.request(ctx, tableCommand(cmd), func(items []string) error {
+		p := newFieldParser("IndustryCode", strings.Join(items, ","), items, nil)
+		rows = append(rows, &IndustryCode{Code: p.int(0, "Code"), Description: p.rest(1, "Description")})
+		return p.error()
+	})
+	return rows, err
+}
+
+func tableCommand(cmd string) func(id string) string {
+	return func(id string) string { return cmd + "," + id + "\r\n" }
+}
+
+// MarketTables caches the lookup tables needed to decode the IDs and codes sent in the Level 1 messages.
+type MarketTables struct {
+	ListedMarkets   map[int]*ListedMarket
+	SecurityTypes   map[int]*CodeName
+	TradeConditions map[int]*CodeName
+	SICCodes        map[int]string
+	NAICSCodes      map[int]string
+	conditions      *TradeConditionTable // Built from TradeConditions by RefreshMarketTables.
+}
+
+// MarketTables returns the market tables, they are fetched from the lookup port on the first call and cached afterwards.
+// Fetching the tables also refreshes DefaultTradeConditions, so UpdSummaryMsg.TradeConditions and ParseTradeConditions
+// decode the conditions with the table served by IQConnect.
+func (l *LookupClient) MarketTables(ctx context.Context) (*MarketTables, error) {
+	l.mu.Lock()
+	t := l.tables
+	l.mu.Unlock()
+	if t != nil {
+		return t, nil
+	}
+	return l.RefreshMarketTables(ctx)
+}
+
+// RefreshMarketTables fetches every market table from the lookup port and replaces the cached copy and the conditions of
+// DefaultTradeConditions.
+func (l *LookupClient) RefreshMarketTables(ctx context.Context) (*MarketTables, error) {
+	t := &MarketTables{
+		ListedMarkets:   make(map[int]*ListedMarket),
+		SecurityTypes:   make(map[int]*CodeName),
+		TradeConditions: make(map[int]*CodeName),
+		SICCodes:        make(map[int]string),
+		NAICSCodes:      make(map[int]string),
+	}
+	markets, err := l.ListedMarkets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range markets {
+		t.ListedMarkets[m.ID] = m
+	}
+	types, err := l.SecurityTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range types {
+		t.SecurityTypes[st.ID] = st
+	}
+	conds, err := l.TradeConditions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, tc := range conds {
+		t.TradeConditions[tc.ID] = tc
+	}
+	t.conditions = &TradeConditionTable{}
+	t.conditions.Refresh(conds)
+	DefaultTradeConditions.Refresh(conds)
+	sics, err := l.SICCodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range sics {
+		t.SICCodes[c.Code] = c.Description
+	}
+	naics, err := l.NAICSCodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range naics {
+		t.NAICSCodes[c.Code] = c.Description
+	}
+	l.mu.Lock()
+	l.tables = t
+	l.mu.Unlock()
+	return t, nil
+}
+
+// ListedMarketName returns the short name of a listed market ID as sent in FundamentalMsg.ListedMarket, or the ID itself when unknown.
+func (t *MarketTables) ListedMarketName(id string) string {
+	if m, ok := t.ListedMarkets[GetIntFromStr(id)]; ok {
+		return m.ShortName
+	}
+	return id
+}
+
+// SecurityTypeName returns the short name of a security type ID as sent in FundamentalMsg.SecurityType, or the ID itself when unknown.
+func (t *MarketTables) SecurityTypeName(id string) string {
+	if st, ok := t.SecurityTypes[GetIntFromStr(id)]; ok {
+		return st.ShortName
+	}
+	return id
+}
+
+// TradeConditionNames decodes a trade condition string as sent in UpdSummaryMsg.MostRecntTradeCond into the short names
+// of its conditions, see TradeConditionTable.Parse. Unknown codes are returned in hex.
+func (t *MarketTables) TradeConditionNames(cond string) []string {
+	table := t.conditions
+	if table == nil {
+		// The tables were not fetched by RefreshMarketTables.
+		table = &TradeConditionTable{}
+		table.RefreshFrom(t)
+	}
+	var names []string
+	for _, c := range table.Parse(cond) {
+		names = append(names, c.ShortName)
+	}
+	return names
+}
+
+// SICName returns the description of a SIC code as sent in FundamentalMsg.SIC.
+func (t *MarketTables) SICName(code int) string {
+	return t.SICCodes[code]
+}
+
+// NAICSName returns the description of a NAICS code as sent in FundamentalMsg.NAICS.
+func (t *MarketTables) NAICSName(code int) string {
+	return t.NAICSCodes[code]
+}
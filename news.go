@@ -14,14 +14,15 @@ type NewsMsg struct {
 	Headline        string    // The text headline
 }
 
-// UnMarshall sends the data into the usable struct for consumption by the application.
-func (n *NewsMsg) UnMarshall(d []byte, loc *time.Location) {
-	items := strings.Split(string(d), ",")
-	n.DistributorCode = items[0]
-	n.StoryID = GetIntFromStr(items[1])
-	n.SymbolList = strings.Split(items[2], ":")
-	t, _ := time.ParseInLocation("20060102 150405", items[3], loc)
-	n.DateTime = t
-	n.Headline = items[4]
-
+// UnMarshall sends the data into the usable struct for consumption by the application, a *ParseError is returned when
+// the line is too short or a field is invalid.
+func (n *NewsMsg) UnMarshall(d []byte, loc *time.Location) error {
+	p := newFieldParser("NewsMsg", string(d), strings.SplitN(string(d), ",", 5), loc)
+	n.DistributorCode = p.str(0, "DistributorCode")
+	n.StoryID = p.int(1, "StoryID")
+	n.SymbolList = strings.Split(p.str(2, "SymbolList"), ":")
+	n.DateTime = p.time(3, "DateTime", "20060102 150405")
+	// The headline is the rest of the line as it may contain commas.
+	n.Headline = strings.TrimSuffix(p.str(4, "Headline"), ",")
+	return p.error()
 }
@@ -0,0 +1,263 @@
+package iqfeed
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NewsFormat selects the format the news lookups are requested in, both are parsed into the same structs.
+type NewsFormat string
+
+const (
+	NewsXML  NewsFormat = "x"
+	NewsText NewsFormat = "t"
+)
+
+func (f NewsFormat) orDefault() NewsFormat {
+	if f == "" {
+		return NewsXML
+	}
+	return f
+}
+
+// NewsSource is a news source from the news configuration, major sources contain their minor sources.
+type NewsSource struct {
+	Category string       // The category the source belongs to (ex: News Agencies)
+	Type     string       // The source code used in headline and story count requests (ex: DTN)
+	Name     string       // Short name of the source
+	FullName string       // Full name of the source
+	AuthCode string       // The authorization code the account needs to receive the source
+	IconID   int          // The icon ID of the source
+	Minor    []NewsSource // The minor sources of a major source
+}
+
+// NewsConfig is the response to a news configuration lookup (NCG).
+type NewsConfig struct {
+	Sources []NewsSource // The major sources, only parsed from the XML format
+	Raw     string       // The response as received, the text format is only available here
+}
+
+// NewsStory is the response to a news story lookup (NSY).
+type NewsStory struct {
+	ID      string   // The story ID that was requested
+	IsLink  bool     // True when Text is a link to the story instead of the story itself
+	Text    string   // The story text
+	Symbols []string // The symbols associated with the story
+}
+
+// HeadlineRequest describes a news headline lookup (NHL).
+type HeadlineRequest struct {
+	Sources []string   // Only return headlines from these sources, empty for all authorized sources.
+	Symbols []string   // Only return headlines for these symbols, empty for all.
+	Limit   int        // The maximum number of headlines to return, 0 for the server default.
+	Date    time.Time  // Only return headlines from this date, or from Date to EndDate when EndDate is set.
+	EndDate time.Time  // End of the date range.
+	Format  NewsFormat // Defaults to NewsXML.
+}
+
+func (r *HeadlineRequest) command(id string, loc *time.Location) string {
+	return fmt.Sprintf("NHL,%s,%s,%s,%s,%s,%s\r\n", strings.Join(r.Sources, ":"), strings.Join(r.Symbols, ":"),
+		r.Format.orDefault(), optInt(r.Limit), newsDateRange(r.Date, r.EndDate, loc), id)
+}
+
+// StoryCountRequest describes a news story count lookup (NSC).
+type StoryCountRequest struct {
+	Symbols []string   // The symbols to count stories for.
+	Sources []string   // Only count stories from these sources, empty for all authorized sources.
+	From    time.Time  // Only count stories from this date.
+	To      time.Time  // Only count stories up to this date.
+	Format  NewsFormat // Defaults to NewsXML.
+}
+
+func (r *StoryCountRequest) command(id string, loc *time.Location) string {
+	return fmt.Sprintf("NSC,%s,%s,%s,%s,%s,%s\r\n", strings.Join(r.Symbols, ":"), r.Format.orDefault(),
+		strings.Join(r.Sources, ":"), newsDate(r.From, loc), newsDate(r.To, loc), id)
+}
+
+func newsDate(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format("20060102")
+}
+
+func newsDateRange(from, to time.Time, loc *time.Location) string {
+	if to.IsZero() {
+		return newsDate(from, loc)
+	}
+	return newsDate(from, loc) + "-" + newsDate(to, loc)
+}
+
+// newsLines runs a news request and returns the response lines, rows are rejoined as XML and text content may contain commas.
+func (l *LookupClient) newsLines(ctx context.Context, cmd func(id string) string) ([]string, error) {
+	var lines []string
+	err := l.request(ctx, cmd, func(items []string) error {
+		lines = append(lines, strings.Join(items, ","))
+		return nil
+	})
+	return lines, err
+}
+
+// NewsConfig returns the news configuration, listing the sources available to the account.
+func (l *LookupClient) NewsConfig(ctx context.Context, format NewsFormat) (*NewsConfig, error) {
+	format = format.orDefault()
+	lines, err := l.newsLines(ctx, func(id string) string { return "NCG," + string(format) + "," + id + "\r\n" })
+	if err != nil {
+		return nil, err
+	}
+	cfg := &NewsConfig{Raw: strings.Join(lines, "\n")}
+	if format != NewsXML {
+		return cfg, nil
+	}
+	var doc struct {
+		Categories []struct {
+			Name  string `xml:"Name,attr"`
+			Major []struct {
+				xmlNewsSource
+				Minor []xmlNewsSource `xml:"Minortype"`
+			} `xml:"Majortype"`
+		} `xml:"Category"`
+	}
+	if err := xml.Unmarshal([]byte(cfg.Raw), &doc); err != nil {
+		return nil, &LookupError{Message: "invalid news configuration: " + err.Error()}
+	}
+	for _, c := range doc.Categories {
+		for _, m := range c.Major {
+			src := m.source(c.Name)
+			for _, mi := range m.Minor {
+				src.Minor = append(src.Minor, mi.source(c.Name))
+			}
+			cfg.Sources = append(cfg.Sources, src)
+		}
+	}
+	return cfg, nil
+}
+
+type xmlNewsSource struct {
+	Type     string `xml:"Type,attr"`
+	Name     string `xml:"Name,attr"`
+	FullName string `xml:"FullName,attr"`
+	AuthCode string `xml:"AuthCode,attr"`
+	IconID   int    `xml:"IconID,attr"`
+}
+
+func (x xmlNewsSource) source(category string) NewsSource {
+	return NewsSource{Category: category, Type: x.Type, Name: x.Name, FullName: x.FullName, AuthCode: x.AuthCode, IconID: x.IconID}
+}
+
+// NewsHeadlines returns the headlines matching the request, parsed into the same struct as the streaming news headlines.
+func (l *LookupClient) NewsHeadlines(ctx context.Context, r *HeadlineRequest) ([]*NewsMsg, error) {
+	lines, err := l.newsLines(ctx, func(id string) string { return r.command(id, l.TimeLoc) })
+	if err != nil {
+		return nil, err
+	}
+	var headlines []*NewsMsg
+	if r.Format.orDefault() == NewsText {
+		for _, line := range lines {
+			// Text headlines use the streaming news layout, optionally prefixed with the N message type.
+			n := &NewsMsg{}
+			if err := n.UnMarshall([]byte(strings.TrimPrefix(line, "N,")), l.TimeLoc); err != nil {
+				return nil, err
+			}
+			headlines = append(headlines, n)
+		}
+		return headlines, nil
+	}
+	var doc struct {
+		Headlines []struct {
+			ID        int    `xml:"id"`
+			Source    string `xml:"source"`
+			Timestamp string `xml:"timestamp"`
+			Symbols   string `xml:"symbols"`
+			Text      string `xml:"text"`
+		} `xml:"news_headline"`
+	}
+	if err := xml.Unmarshal([]byte(strings.Join(lines, "\n")), &doc); err != nil {
+		return nil, &LookupError{Message: "invalid news headlines: " + err.Error()}
+	}
+	for _, h := range doc.Headlines {
+		t, _ := time.ParseInLocation("20060102150405", strings.TrimSpace(h.Timestamp), l.TimeLoc)
+		headlines = append(headlines, &NewsMsg{
+			DistributorCode: h.Source,
+			StoryID:         h.ID,
+			SymbolList:      splitSymbols(h.Symbols),
+			DateTime:        t,
+			Headline:        strings.TrimSpace(h.Text),
+		})
+	}
+	return headlines, nil
+}
+
+// NewsStory returns the text of the story with the ID from a headline.
+func (l *LookupClient) NewsStory(ctx context.Context, id string, format NewsFormat) (*NewsStory, error) {
+	format = format.orDefault()
+	lines, err := l.newsLines(ctx, func(rid string) string { return "NSY," + id + "," + string(format) + ",," + rid + "\r\n" })
+	if err != nil {
+		return nil, err
+	}
+	story := &NewsStory{ID: id}
+	if format == NewsText {
+		story.Text = strings.Join(lines, "\n")
+		return story, nil
+	}
+	var doc struct {
+		Story struct {
+			IsLink  string `xml:"is_link"`
+			Text    string `xml:"story_text"`
+			Symbols string `xml:"symbols"`
+		} `xml:"news_story"`
+	}
+	if err := xml.Unmarshal([]byte(strings.Join(lines, "\n")), &doc); err != nil {
+		return nil, &LookupError{Message: "invalid news story: " + err.Error()}
+	}
+	story.IsLink = strings.EqualFold(strings.TrimSpace(doc.Story.IsLink), "true")
+	story.Text = strings.TrimSpace(doc.Story.Text)
+	story.Symbols = splitSymbols(doc.Story.Symbols)
+	return story, nil
+}
+
+// NewsStoryCounts returns the number of stories for each symbol in the request.
+func (l *LookupClient) NewsStoryCounts(ctx context.Context, r *StoryCountRequest) (map[string]int, error) {
+	lines, err := l.newsLines(ctx, func(id string) string { return r.command(id, l.TimeLoc) })
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	if r.Format.orDefault() == NewsText {
+		// Text counts are sent as [Symbol]:[Count]:[Symbol]:[Count]...
+		for _, line := range lines {
+			parts := strings.Split(strings.Trim(line, ":"), ":")
+			for i := 0; i+1 < len(parts); i += 2 {
+				counts[parts[i]] = GetIntFromStr(parts[i+1])
+			}
+		}
+		return counts, nil
+	}
+	var doc struct {
+		Symbols []struct {
+			Name  string `xml:"Name,attr"`
+			Count int    `xml:"StoryCount,attr"`
+		} `xml:"symbol"`
+	}
+	if err := xml.Unmarshal([]byte(strings.Join(lines, "\n")), &doc); err != nil {
+		return nil, &LookupError{Message: "invalid news story counts: " + err.Error()}
+	}
+	for _, s := range doc.Symbols {
+		counts[s.Name] = s.Count
+	}
+	return counts, nil
+}
+
+// splitSymbols splits a colon delimited symbol list, dropping the empty entries at either end.
+func splitSymbols(d string) []string {
+	var syms []string
+	for _, s := range strings.Split(strings.TrimSpace(d), ":") {
+		if s != "" {
+			syms = append(syms, s)
+		}
+	}
+	return syms
+}
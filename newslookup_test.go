@@ -0,0 +1,134 @@
+package iqfeed
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newsReplies holds the response lines of each news lookup by command and format.
+var newsReplies = map[string]map[NewsFormat][]string{
+	"NCG": {
+		NewsXML: {
+			`<?xml version="1.0" ?>`,
+			`<DynamicNewsConf>`,
+			`<Category Name="News Agencies">`,
+			`<Majortype Type="DTN" Name="DTN News" FullName="DTN News Service" AuthCode="1" IconID="10">`,
+			`<Minortype Type="DTNF" Name="DTN Futures" FullName="DTN Futures News" AuthCode="1" IconID="11"/>`,
+			`</Majortype>`,
+			`</Category>`,
+			`</DynamicNewsConf>`,
+		},
+		NewsText: {"News Agencies", "DTN:DTN News:DTN News Service:1:10"},
+	},
+	"NHL": {
+		NewsXML: {
+			`<news_headlines>`,
+			`<news_headline>`,
+			`<id>22587641</id>`,
+			`<source>DTN</source>`,
+			`<timestamp>20240305093000</timestamp>`,
+			`<symbols>:AAPL:MSFT:</symbols>`,
+			`<text>Apple, Microsoft rally</text>`,
+			`</news_headline>`,
+			`</news_headlines>`,
+		},
+		NewsText: {"N,DTN,22587641,AAPL:MSFT,20240305 093000,Apple, Microsoft rally"},
+	},
+	"NSY": {
+		NewsXML: {
+			`<news_stories><news_story>`,
+			`<is_link>false</is_link>`,
+			`<story_text>Shares rose, then fell.</story_text>`,
+			`<symbols>:AAPL:</symbols>`,
+			`</news_story></news_stories>`,
+		},
+		NewsText: {"Shares rose, then fell."},
+	},
+	"NSC": {
+		NewsXML:  {`<story_counts>`, `<symbol Name="AAPL" StoryCount="12"/>`, `<symbol Name="MSFT" StoryCount="3"/>`, `</story_counts>`},
+		NewsText: {"AAPL:12:MSFT:3:"},
+	},
+}
+
+func TestNewsLookups(t *testing.T) {
+	for _, format := range []NewsFormat{NewsXML, NewsText} {
+		t.Run(string(format), func(t *testing.T) {
+			l, cmds := startLookup(t, "", func(cmd, id string) []string {
+				var lines []string
+				for _, line := range newsReplies[cmd[:3]][format] {
+					lines = append(lines, "{id},"+line)
+				}
+				return append(lines, "{id},!ENDMSG!,")
+			})
+			ctx := context.Background()
+			f := string(format)
+
+			cfg, err := l.NewsConfig(ctx, format)
+			if err != nil {
+				t.Fatalf("NewsConfig: %s", err)
+			}
+			if cmd := <-cmds; cmd != "NCG,"+f+",L1" {
+				t.Errorf("command = %q", cmd)
+			}
+			if cfg.Raw != strings.Join(newsReplies["NCG"][format], "\n") {
+				t.Errorf("news config raw = %q", cfg.Raw)
+			}
+			if format == NewsXML {
+				want := []NewsSource{{Category: "News Agencies", Type: "DTN", Name: "DTN News", FullName: "DTN News Service", AuthCode: "1", IconID: 10,
+					Minor: []NewsSource{{Category: "News Agencies", Type: "DTNF", Name: "DTN Futures", FullName: "DTN Futures News", AuthCode: "1", IconID: 11}}}}
+				if !reflect.DeepEqual(cfg.Sources, want) {
+					t.Errorf("news sources = %+v, want %+v", cfg.Sources, want)
+				}
+			}
+
+			headlines, err := l.NewsHeadlines(ctx, &HeadlineRequest{Sources: []string{"DTN", "CPR"}, Symbols: []string{"AAPL"}, Limit: 10,
+				Date: time.Date(2024, time.March, 5, 0, 0, 0, 0, l.TimeLoc), Format: format})
+			if err != nil {
+				t.Fatalf("NewsHeadlines: %s", err)
+			}
+			if cmd := <-cmds; cmd != "NHL,DTN:CPR,AAPL,"+f+",10,20240305,L2" {
+				t.Errorf("command = %q", cmd)
+			}
+			want := &NewsMsg{DistributorCode: "DTN", StoryID: 22587641, SymbolList: []string{"AAPL", "MSFT"},
+				DateTime: time.Date(2024, time.March, 5, 9, 30, 0, 0, l.TimeLoc), Headline: "Apple, Microsoft rally"}
+			if len(headlines) != 1 || !headlines[0].DateTime.Equal(want.DateTime) {
+				t.Fatalf("headlines = %+v", headlines)
+			}
+			headlines[0].DateTime = want.DateTime
+			if !reflect.DeepEqual(headlines[0], want) {
+				t.Errorf("headline = %+v, want %+v", headlines[0], want)
+			}
+
+			story, err := l.NewsStory(ctx, "22587641", format)
+			if err != nil {
+				t.Fatalf("NewsStory: %s", err)
+			}
+			if cmd := <-cmds; cmd != "NSY,22587641,"+f+",,L3" {
+				t.Errorf("command = %q", cmd)
+			}
+			wantStory := &NewsStory{ID: "22587641", Text: "Shares rose, then fell."}
+			if format == NewsXML {
+				// Only the XML story carries its symbols.
+				wantStory.Symbols = []string{"AAPL"}
+			}
+			if !reflect.DeepEqual(story, wantStory) {
+				t.Errorf("story = %+v, want %+v", story, wantStory)
+			}
+
+			counts, err := l.NewsStoryCounts(ctx, &StoryCountRequest{Symbols: []string{"AAPL", "MSFT"}, Format: format,
+				From: time.Date(2024, time.March, 1, 0, 0, 0, 0, l.TimeLoc), To: time.Date(2024, time.March, 5, 0, 0, 0, 0, l.TimeLoc)})
+			if err != nil {
+				t.Fatalf("NewsStoryCounts: %s", err)
+			}
+			if cmd := <-cmds; cmd != "NSC,AAPL:MSFT,"+f+",,20240301,20240305,L4" {
+				t.Errorf("command = %q", cmd)
+			}
+			if want := map[string]int{"AAPL": 12, "MSFT": 3}; !reflect.DeepEqual(counts, want) {
+				t.Errorf("story counts = %v, want %v", counts, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package iqfeed
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// futureMonthCodes are the exchange month codes for futures contracts, January through December.
+const futureMonthCodes = "FGHJKMNQUVXZ"
+
+var (
+	// [Root][YY][DD][Month code A-L calls, M-X puts][Strike], ex: AAPL2118L150 or SPY2119I447.5.
+	optionSymbolRe = regexp.MustCompile(`^(.+?)(\d{2})(\d{2})([A-X])(\d+(?:\.\d+)?)$`)
+	// [Root][Month code][YY], ex: @ESZ24.
+	futureSymbolRe = regexp.MustCompile(`^(.+?)([FGHJKMNQUVXZ])(\d{2})$`)
+	// [Root][Month code][YY][C/P][Strike], ex: @ESZ24C5000.
+	futureOptionSymbolRe = regexp.MustCompile(`^(.+?)([FGHJKMNQUVXZ])(\d{2})([CP])(\d+(?:\.\d+)?)$`)
+)
+
+// OptionContract is an equity or index option contract parsed from an IQFeed option symbol.
+type OptionContract struct {
+	Symbol     string    // The IQFeed symbol used in watch requests
+	Root       string    // The option root symbol (ex: AAPL or an adjusted root such as AAPL7)
+	Expiration time.Time // Expiration date
+	Strike     float64   // Strike price
+	IsCall     bool      // True for calls, false for puts
+}
+
+// FutureContract is a futures contract parsed from an IQFeed futures symbol.
+type FutureContract struct {
+	Symbol string     // The IQFeed symbol used in watch requests
+	Root   string     // The futures root symbol (ex: @ES)
+	Month  time.Month // Contract month
+	Year   int        // Contract year
+}
+
+// FutureOptionContract is an option on a futures contract parsed from an IQFeed future option symbol.
+type FutureOptionContract struct {
+	FutureContract
+	Strike float64 // Strike price as sent in the symbol, some exchanges imply decimal places
+	IsCall bool    // True for calls, false for puts
+}
+
+// optionMonthCode returns the month code used in option symbols, A-L for calls and M-X for puts.
+func optionMonthCode(m time.Month, isCall bool) byte {
+	if isCall {
+		return 'A' + byte(m-1)
+	}
+	return 'M' + byte(m-1)
+}
+
+// OptionSymbol builds the IQFeed symbol for an equity or index option (ex: AAPL2118L150 is the AAPL 150 call expiring 2021-12-18).
+func OptionSymbol(root string, expiration time.Time, strike float64, isCall bool) string {
+	return fmt.Sprintf("%s%s%c%s", root, expiration.Format("0602"), optionMonthCode(expiration.Month(), isCall),
+		strconv.FormatFloat(strike, 'f', -1, 64))
+}
+
+// ParseOptionSymbol parses an IQFeed equity or index option symbol, the expiration date is returned in loc.
+func ParseOptionSymbol(symbol string, loc *time.Location) (*OptionContract, error) {
+	m := optionSymbolRe.FindStringSubmatch(symbol)
+	if m == nil {
+		return nil, fmt.Errorf("iqfeed: invalid option symbol %q", symbol)
+	}
+	code := m[4][0]
+	isCall := code <= 'L'
+	month := time.Month(code-'A') + 1
+	if !isCall {
+		month = time.Month(code-'M') + 1
+	}
+	year, day := GetIntFromStr(m[2]), GetIntFromStr(m[3])
+	strike, err := strconv.ParseFloat(m[5], 64)
+	if err != nil {
+		return nil, fmt.Errorf("iqfeed: invalid option strike in %q: %s", symbol, err)
+	}
+	exp := time.Date(2000+year, month, day, 0, 0, 0, 0, loc)
+	if exp.Day() != day {
+		return nil, fmt.Errorf("iqfeed: invalid option expiration in %q", symbol)
+	}
+	return &OptionContract{Symbol: symbol, Root: m[1], Expiration: exp, Strike: strike, IsCall: isCall}, nil
+}
+
+// ParseFutureSymbol parses an IQFeed futures symbol (ex: @ESZ24).
+func ParseFutureSymbol(symbol string) (*FutureContract, error) {
+	m := futureSymbolRe.FindStringSubmatch(symbol)
+	if m == nil {
+		return nil, fmt.Errorf("iqfeed: invalid future symbol %q", symbol)
+	}
+	return &FutureContract{
+		Symbol: symbol,
+		Root:   m[1],
+		Month:  time.Month(strings.IndexByte(futureMonthCodes, m[2][0]) + 1),
+		Year:   2000 + GetIntFromStr(m[3]),
+	}, nil
+}
+
+// ParseFutureOptionSymbol parses an IQFeed future option symbol (ex: @ESZ24C5000).
+func ParseFutureOptionSymbol(symbol string) (*FutureOptionContract, error) {
+	m := futureOptionSymbolRe.FindStringSubmatch(symbol)
+	if m == nil {
+		return nil, fmt.Errorf("iqfeed: invalid future option symbol %q", symbol)
+	}
+	strike, err := strconv.ParseFloat(m[5], 64)
+	if err != nil {
+		return nil, fmt.Errorf("iqfeed: invalid future option strike in %q: %s", symbol, err)
+	}
+	return &FutureOptionContract{
+		FutureContract: FutureContract{
+			Symbol: symbol,
+			Root:   m[1],
+			Month:  time.Month(strings.IndexByte(futureMonthCodes, m[2][0]) + 1),
+			Year:   2000 + GetIntFromStr(m[3]),
+		},
+		Strike: strike,
+		IsCall: m[4] == "C",
+	}, nil
+}
+
+// EquityChainRequest describes an equity option chain lookup, CEO for the standard chain or CEV when NonStandard is set.
+type EquityChainRequest struct {
+	Symbol        string
+	NonStandard   bool         // Include the non-standard (adjusted) option roots, ex: AAPL7 after a split (CEV).
+	Calls         bool         // Return calls, when neither Calls nor Puts is set both are returned.
+	Puts          bool         // Return puts.
+	Months        []time.Month // Only return these expiration months, empty to use NearMonths.
+	NearMonths    int          // The number of near months to return when Months is empty.
+	IncludeBinary bool         // Include binary options.
+	StrikeLow     float64      // Only return strikes in the range StrikeLow to StrikeHigh.
+	StrikeHigh    float64
+	InTheMoney    int // Only return this many contracts in the money, used when no strike range is set.
+	OutOfTheMoney int // Only return this many contracts out of the money, used when no strike range is set.
+}
+
+func (r *EquityChainRequest) command(id string) string {
+	calls, puts := r.Calls || !r.Puts, r.Puts || !r.Calls
+	var side, months string
+	if puts {
+		side = "p"
+	}
+	if calls {
+		side += "c"
+	}
+	for _, m := range r.Months {
+		if calls {
+			months += string(optionMonthCode(m, true))
+		}
+		if puts {
+			months += string(optionMonthCode(m, false))
+		}
+	}
+	near := ""
+	if len(r.Months) == 0 {
+		near = strconv.Itoa(r.NearMonths)
+	}
+	filter, v1, v2 := "0", "", ""
+	switch {
+	case r.StrikeLow != 0 || r.StrikeHigh != 0:
+		filter = "1"
+		v1, v2 = strconv.FormatFloat(r.StrikeLow, 'f', -1, 64), strconv.FormatFloat(r.StrikeHigh, 'f', -1, 64)
+	case r.InTheMoney != 0 || r.OutOfTheMoney != 0:
+		filter = "2"
+		v1, v2 = strconv.Itoa(r.InTheMoney), strconv.Itoa(r.OutOfTheMoney)
+	}
+	cmd := "CEO"
+	if r.NonStandard {
+		cmd = "CEV"
+	}
+	return fmt.Sprintf("%s,%s,%s,%s,%s,%s,%s,%s,%s,%s\r\n", cmd, r.Symbol, side, months, near, boolFlag(r.IncludeBinary), filter, v1, v2, id)
+}
+
+// FutureChainRequest describes a futures chain (CFU) or future option chain (CFO) lookup.
+type FutureChainRequest struct {
+	Symbol     string
+	Calls      bool         // Future option chains only, when neither Calls nor Puts is set both are returned.
+	Puts       bool         // Future option chains only.
+	Months     []time.Month // Only return these contract months, empty for all.
+	Years      []int        // Only return contracts expiring in these years.
+	NearMonths int          // The number of near months to return when Months is empty.
+}
+
+// Args returns the month, year and near month parameters shared by CFU and CFO, months outside January to December are rejected.
+func (r *FutureChainRequest) args() (string, error) {
+	if err := checkMonths(r.Months); err != nil {
+		return "", err
+	}
+	var months, years string
+	for _, m := range r.Months {
+		months += string(futureMonthCodes[m-1])
+	}
+	for _, y := range r.Years {
+		years += strconv.Itoa(y % 10)
+	}
+	near := ""
+	if len(r.Months) == 0 {
+		near = optInt(r.NearMonths)
+	}
+	return months + "," + years + "," + near, nil
+}
+
+// checkMonths returns an error when a requested contract month is not a calendar month, the month codes are looked up by it.
+func checkMonths(months []time.Month) error {
+	for _, m := range months {
+		if m < time.January || m > time.December {
+			return fmt.Errorf("iqfeed: invalid chain month %d", m)
+		}
+	}
+	return nil
+}
+
+// chainSymbols returns every symbol in a chain response, chains are sent as colon delimited symbols with calls and puts
+// separated by an empty entry.
+func chainSymbols(items []string) []string {
+	var syms []string
+	for _, item := range items {
+		for _, s := range strings.Split(item, ":") {
+			if s = strings.TrimSpace(s); s != "" {
+				syms = append(syms, s)
+			}
+		}
+	}
+	return syms
+}
+
+// EquityOptionChain returns the parsed contracts of an equity option chain.
+func (l *LookupClient) EquityOptionChain(ctx context.Context, r *EquityChainRequest) ([]*OptionContract, error) {
+	if err := checkMonths(r.Months); err != nil {
+		return nil, err
+	}
+	var contracts []*OptionContract
+	err := l.request(ctx, r.command, func(items []string) error {
+		for _, s := range chainSymbols(items) {
+			c, err := ParseOptionSymbol(s, l.TimeLoc)
+			if err != nil {
+				return err
+			}
+			contracts = append(contracts, c)
+		}
+		return nil
+	})
+	return contracts, err
+}
+
+// FutureChain returns the parsed contracts of a futures chain.
+func (l *LookupClient) FutureChain(ctx context.Context, r *FutureChainRequest) ([]*FutureContract, error) {
+	args, err := r.args()
+	if err != nil {
+		return nil, err
+	}
+	var contracts []*FutureContract
+	cmd := func(id string) string { return "CFU," + r.Symbol + "," + args + "," + id + "\r\n" }
+	err = l.request(ctx, cmd, func(items []string) error {
+		for _, s := range chainSymbols(items) {
+			c, err := ParseFutureSymbol(s)
+			if err != nil {
+				return err
+			}
+			contracts = append(contracts, c)
+		}
+		return nil
+	})
+	return contracts, err
+}
+
+// FutureOptionChain returns the parsed contracts of a future option chain.
+func (l *LookupClient) FutureOptionChain(ctx context.Context, r *FutureChainRequest) ([]*FutureOptionContract, error) {
+	args, err := r.args()
+	if err != nil {
+		return nil, err
+	}
+	side := "pc"
+	switch {
+	case r.Calls && !r.Puts:
+		side = "c"
+	case r.Puts && !r.Calls:
+		side = "p"
+	}
+	var contracts []*FutureOptionContract
+	cmd := func(id string) string { return "CFO," + r.Symbol + "," + side + "," + args + "," + id + "\r\n" }
+	err = l.request(ctx, cmd, func(items []string) error {
+		for _, s := range chainSymbols(items) {
+			c, err := ParseFutureOptionSymbol(s)
+			if err != nil {
+				return err
+			}
+			contracts = append(contracts, c)
+		}
+		return nil
+	})
+	return contracts, err
+}
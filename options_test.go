@@ -0,0 +1,169 @@
+package iqfeed
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOptionSymbolRoundTrip(t *testing.T) {
+	tests := []struct {
+		root   string
+		exp    time.Time
+		strike float64
+		isCall bool
+		symbol string
+	}{
+		{"AAPL", time.Date(2021, time.December, 18, 0, 0, 0, 0, time.UTC), 150, true, "AAPL2118L150"},
+		{"SPY", time.Date(2021, time.September, 19, 0, 0, 0, 0, time.UTC), 447.5, true, "SPY2119I447.5"},
+		{"AAPL7", time.Date(2018, time.January, 18, 0, 0, 0, 0, time.UTC), 180, false, "AAPL71818M180"},
+	}
+	for _, tt := range tests {
+		sym := OptionSymbol(tt.root, tt.exp, tt.strike, tt.isCall)
+		if sym != tt.symbol {
+			t.Errorf("OptionSymbol(%s) = %s, want %s", tt.root, sym, tt.symbol)
+		}
+		c, err := ParseOptionSymbol(sym, time.UTC)
+		if err != nil {
+			t.Fatalf("ParseOptionSymbol(%s): %s", sym, err)
+		}
+		if c.Root != tt.root || !c.Expiration.Equal(tt.exp) || c.Strike != tt.strike || c.IsCall != tt.isCall {
+			t.Errorf("ParseOptionSymbol(%s) = %+v", sym, c)
+		}
+	}
+	if _, err := ParseOptionSymbol("MSFT", time.UTC); err == nil {
+		t.Error("ParseOptionSymbol accepted a symbol that is not an option")
+	}
+}
+
+func TestParseFutureSymbols(t *testing.T) {
+	f, err := ParseFutureSymbol("@ESZ24")
+	if err != nil || f.Root != "@ES" || f.Month != time.December || f.Year != 2024 {
+		t.Errorf("ParseFutureSymbol(@ESZ24) = %+v, %v", f, err)
+	}
+	o, err := ParseFutureOptionSymbol("@ESH25P5000")
+	if err != nil || o.Root != "@ES" || o.Month != time.March || o.Year != 2025 || o.IsCall || o.Strike != 5000 {
+		t.Errorf("ParseFutureOptionSymbol(@ESH25P5000) = %+v, %v", o, err)
+	}
+}
+
+func TestChainCommands(t *testing.T) {
+	l, cmds := startLookup(t, "", func(cmd, id string) []string { return []string{"{id},!ENDMSG!,"} })
+	ctx := context.Background()
+	equity := func(r *EquityChainRequest) func() error {
+		return func() error { _, err := l.EquityOptionChain(ctx, r); return err }
+	}
+	futures := func(r *FutureChainRequest) func() error {
+		return func() error { _, err := l.FutureChain(ctx, r); return err }
+	}
+	futureOptions := func(r *FutureChainRequest) func() error {
+		return func() error { _, err := l.FutureOptionChain(ctx, r); return err }
+	}
+	tests := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{"CEO near months", equity(&EquityChainRequest{Symbol: "AAPL", NearMonths: 1}), "CEO,AAPL,pc,,1,0,0,,,L1"},
+		{"CEO calls strike range", equity(&EquityChainRequest{Symbol: "AAPL", Calls: true, Months: []time.Month{time.December, time.January},
+			StrikeLow: 140, StrikeHigh: 162.5}), "CEO,AAPL,c,LA,,0,1,140,162.5,L2"},
+		{"CEO puts in the money", equity(&EquityChainRequest{Symbol: "AAPL", Puts: true, Months: []time.Month{time.March}, IncludeBinary: true,
+			InTheMoney: 2, OutOfTheMoney: 3}), "CEO,AAPL,p,O,,1,2,2,3,L3"},
+		{"CEO both sides", equity(&EquityChainRequest{Symbol: "AAPL", Months: []time.Month{time.March}}), "CEO,AAPL,pc,CO,,0,0,,,L4"},
+		{"CEV", equity(&EquityChainRequest{Symbol: "AAPL", NonStandard: true, NearMonths: 2}), "CEV,AAPL,pc,,2,0,0,,,L5"},
+		{"CFU months", futures(&FutureChainRequest{Symbol: "@ES", Months: []time.Month{time.March, time.June}, Years: []int{2024, 2025}}),
+			"CFU,@ES,HM,45,,L6"},
+		{"CFU near months", futures(&FutureChainRequest{Symbol: "@ES", NearMonths: 4}), "CFU,@ES,,,4,L7"},
+		{"CFO calls", futureOptions(&FutureChainRequest{Symbol: "@ES", Calls: true, Months: []time.Month{time.December}, Years: []int{2024}}),
+			"CFO,@ES,c,Z,4,,L8"},
+		{"CFO both sides", futureOptions(&FutureChainRequest{Symbol: "@ES", NearMonths: 1}), "CFO,@ES,pc,,,1,L9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err != nil {
+				t.Fatal(err)
+			}
+			if cmd := <-cmds; cmd != tt.want {
+				t.Errorf("command = %q, want %q", cmd, tt.want)
+			}
+		})
+	}
+
+	// Months that have no month code are rejected before anything is sent.
+	for _, run := range []func() error{
+		equity(&EquityChainRequest{Symbol: "AAPL", Months: []time.Month{13}}),
+		futures(&FutureChainRequest{Symbol: "@ES", Months: []time.Month{0}}),
+		futureOptions(&FutureChainRequest{Symbol: "@ES", Months: []time.Month{time.March, 13}}),
+	} {
+		if err := run(); err == nil {
+			t.Error("a chain request with an invalid month was accepted")
+		}
+	}
+	if len(cmds) != 0 {
+		t.Errorf("sent %q for an invalid month", <-cmds)
+	}
+}
+
+func TestChainParsing(t *testing.T) {
+	l, _ := startLookup(t, "", func(cmd, id string) []string {
+		switch cmd[:3] {
+		case "CEO", "CEV":
+			return []string{"{id},AAPL2118L150:AAPL2118L155:AAPL71818A180::AAPL2118X150:,", "{id},!ENDMSG!,"}
+		case "CFU":
+			return []string{"{id},@ESH25:@ESM25:@ESZ24:,", "{id},!ENDMSG!,"}
+		}
+		return []string{"{id},@ESZ24C5000:@ESZ24C5025.5::@ESZ24P5000:,", "{id},!ENDMSG!,"}
+	})
+	ctx := context.Background()
+
+	options, err := l.EquityOptionChain(ctx, &EquityChainRequest{Symbol: "AAPL", NonStandard: true})
+	if err != nil {
+		t.Fatalf("EquityOptionChain: %s", err)
+	}
+	var got []string
+	for _, c := range options {
+		side := "P"
+		if c.IsCall {
+			side = "C"
+		}
+		got = append(got, fmt.Sprintf("%s %s %s %g %s", c.Symbol, c.Root, c.Expiration.Format("2006-01-02"), c.Strike, side))
+	}
+	want := []string{
+		"AAPL2118L150 AAPL 2021-12-18 150 C",
+		"AAPL2118L155 AAPL 2021-12-18 155 C",
+		"AAPL71818A180 AAPL7 2018-01-18 180 C",
+		"AAPL2118X150 AAPL 2021-12-18 150 P",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("equity chain = %q, want %q", got, want)
+	}
+	if options[0].Expiration.Location() != l.TimeLoc {
+		t.Errorf("expiration location = %s, want %s", options[0].Expiration.Location(), l.TimeLoc)
+	}
+
+	futures, err := l.FutureChain(ctx, &FutureChainRequest{Symbol: "@ES"})
+	if err != nil {
+		t.Fatalf("FutureChain: %s", err)
+	}
+	got = nil
+	for _, c := range futures {
+		got = append(got, fmt.Sprintf("%s %s %s %d", c.Symbol, c.Root, c.Month, c.Year))
+	}
+	if want := []string{"@ESH25 @ES March 2025", "@ESM25 @ES June 2025", "@ESZ24 @ES December 2024"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("future chain = %q, want %q", got, want)
+	}
+
+	futureOptions, err := l.FutureOptionChain(ctx, &FutureChainRequest{Symbol: "@ES"})
+	if err != nil {
+		t.Fatalf("FutureOptionChain: %s", err)
+	}
+	got = nil
+	for _, c := range futureOptions {
+		got = append(got, fmt.Sprintf("%s %s %g %t", c.Symbol, c.Month, c.Strike, c.IsCall))
+	}
+	if want := []string{"@ESZ24C5000 December 5000 true", "@ESZ24C5025.5 December 5025.5 true", "@ESZ24P5000 December 5000 false"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("future option chain = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+package iqfeed
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// BookLevel is a single side of a market maker or price level quote in an order book snapshot.
+type BookLevel struct {
+	MMID  string    // Market maker or price level ID
+	Price float64   // Bid or ask price
+	Size  int       // Bid or ask size
+	Time  time.Time // Time of the last bid or ask
+}
+
+// BookSnapshot is a point in time copy of the top of an order book.
+type BookSnapshot struct {
+	Symbol  string
+	Bids    []BookLevel // Sorted best (highest) bid first
+	Asks    []BookLevel // Sorted best (lowest) ask first
+	Updated time.Time   // Time of the last update applied to the book
+}
+
+// OrderBook is the in-memory depth of a symbol, reconstructed from the Level 2 summary and update messages.
+// It is safe for concurrent use, the L2Client applies updates while the application takes snapshots.
+type OrderBook struct {
+	Symbol  string
+	mu      sync.RWMutex
+	bids    map[string]BookLevel
+	asks    map[string]BookLevel
+	updated time.Time
+}
+
+// NewOrderBook returns an empty order book for the symbol.
+func NewOrderBook(symbol string) *OrderBook {
+	return &OrderBook{Symbol: symbol, bids: make(map[string]BookLevel), asks: make(map[string]BookLevel)}
+}
+
+// Apply updates the book with a depth message, sides that are flagged as not valid are removed from the book.
+func (b *OrderBook) Apply(m *DepthMsg) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if m.BidInfoValid {
+		b.bids[m.MMID] = BookLevel{MMID: m.MMID, Price: m.Bid, Size: m.BidSize, Time: m.BidTime}
+	} else {
+		delete(b.bids, m.MMID)
+	}
+	if m.AskInfoValid {
+		b.asks[m.MMID] = BookLevel{MMID: m.MMID, Price: m.Ask, Size: m.AskSize, Time: m.AskTime}
+	} else {
+		delete(b.asks, m.MMID)
+	}
+	if m.BidTime.After(b.updated) {
+		b.updated = m.BidTime
+	}
+	if m.AskTime.After(b.updated) {
+		b.updated = m.AskTime
+	}
+}
+
+// Reset removes every level from the book.
+func (b *OrderBook) Reset() {
+	b.mu.Lock()
+	b.bids = make(map[string]BookLevel)
+	b.asks = make(map[string]BookLevel)
+	b.updated = time.Time{}
+	b.mu.Unlock()
+}
+
+// Bids returns the top n bids, best first, n <= 0 returns every bid.
+func (b *OrderBook) Bids(n int) []BookLevel {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return topLevels(b.bids, n, bidBetter)
+}
+
+// Asks returns the top n asks, best first, n <= 0 returns every ask.
+func (b *OrderBook) Asks(n int) []BookLevel {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return topLevels(b.asks, n, askBetter)
+}
+
+// Snapshot returns the top n bids and asks of the book, n <= 0 returns the whole book.
+func (b *OrderBook) Snapshot(n int) *BookSnapshot {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return &BookSnapshot{
+		Symbol:  b.Symbol,
+		Bids:    topLevels(b.bids, n, bidBetter),
+		Asks:    topLevels(b.asks, n, askBetter),
+		Updated: b.updated,
+	}
+}
+
+func bidBetter(x, y BookLevel) bool { return x.Price > y.Price }
+
+func askBetter(x, y BookLevel) bool { return x.Price < y.Price }
+
+// TopLevels sorts the levels by price using better, earlier quotes win ties, and returns the first n.
+func topLevels(levels map[string]BookLevel, n int, better func(x, y BookLevel) bool) []BookLevel {
+	out := make([]BookLevel, 0, len(levels))
+	for _, l := range levels {
+		out = append(out, l)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Price != out[j].Price {
+			return better(out[i], out[j])
+		}
+		if !out[i].Time.Equal(out[j].Time) {
+			return out[i].Time.Before(out[j].Time)
+		}
+		return out[i].MMID < out[j].MMID
+	})
+	if n > 0 && n < len(out) {
+		out = out[:n]
+	}
+	return out
+}
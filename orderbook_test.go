@@ -0,0 +1,175 @@
+package iqfeed
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// levels formats book levels as MMID price x size so they can be compared in one go.
+func levels(ls []BookLevel) string {
+	s := make([]string, len(ls))
+	for i, l := range ls {
+		s[i] = l.MMID + " " + strconv.FormatFloat(l.Price, 'f', -1, 64) + "x" + strconv.Itoa(l.Size)
+	}
+	return strings.Join(s, ", ")
+}
+
+func TestOrderBook(t *testing.T) {
+	tests := []struct {
+		name       string
+		lines      []string
+		n          int
+		bids, asks string
+	}{
+		{"insert", []string{
+			"Z,AAPL,NSDQ,170.12,170.14,100,200,09:30:00,2024-03-05,52,09:30:00,T,T,",
+			"Z,AAPL,ARCA,170.13,170.15,300,400,09:30:01,2024-03-05,52,09:30:01,T,T,",
+			"Z,AAPL,EDGX,170.10,170.13,500,600,09:30:02,2024-03-05,52,09:30:02,T,T,T,",
+		}, 0, "ARCA 170.13x300, NSDQ 170.12x100, EDGX 170.1x500", "EDGX 170.13x600, NSDQ 170.14x200, ARCA 170.15x400"},
+		{"update", []string{
+			"Z,AAPL,NSDQ,170.12,170.14,100,200,09:30:00,2024-03-05,52,09:30:00,T,T,",
+			"Z,AAPL,ARCA,170.13,170.15,300,400,09:30:01,2024-03-05,52,09:30:01,T,T,",
+			"2,AAPL,NSDQ,170.14,170.16,700,800,09:30:05,2024-03-05,52,09:30:05,T,T,T,",
+		}, 0, "NSDQ 170.14x700, ARCA 170.13x300", "ARCA 170.15x400, NSDQ 170.16x800"},
+		{"delete", []string{
+			"Z,AAPL,NSDQ,170.12,170.14,100,200,09:30:00,2024-03-05,52,09:30:00,T,T,",
+			"Z,AAPL,ARCA,170.13,170.15,300,400,09:30:01,2024-03-05,52,09:30:01,T,T,",
+			"2,AAPL,ARCA,0,170.15,0,400,09:30:05,2024-03-05,52,09:30:01,F,T,",
+			"2,AAPL,NSDQ,0,0,0,0,09:30:06,2024-03-05,52,09:30:06,F,F,T,",
+		}, 0, "", "ARCA 170.15x400"},
+		{"top n", []string{
+			"Z,AAPL,NSDQ,170.12,170.14,100,200,09:30:00,2024-03-05,52,09:30:00,T,T,",
+			"Z,AAPL,ARCA,170.13,170.15,300,400,09:30:01,2024-03-05,52,09:30:01,T,T,",
+			"Z,AAPL,EDGX,170.10,170.13,500,600,09:30:02,2024-03-05,52,09:30:02,T,T,T,",
+		}, 2, "ARCA 170.13x300, NSDQ 170.12x100", "EDGX 170.13x600, NSDQ 170.14x200"},
+		{"earlier quote wins a tie", []string{
+			"Z,AAPL,NSDQ,170.12,170.14,100,200,09:30:03,2024-03-05,52,09:30:03,T,T,",
+			"Z,AAPL,ARCA,170.12,170.14,300,400,09:30:01,2024-03-05,52,09:30:01,T,T,",
+		}, 1, "ARCA 170.12x300", "ARCA 170.14x400"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewOrderBook("AAPL")
+			var last time.Time
+			for _, line := range tt.lines {
+				m := &DepthMsg{}
+				if err := m.UnMarshall(line[:1], []byte(line[2:]), time.UTC); err != nil {
+					t.Fatalf("UnMarshall(%q): %s", line, err)
+				}
+				b.Apply(m)
+				if m.BidTime.After(last) {
+					last = m.BidTime
+				}
+			}
+			s := b.Snapshot(tt.n)
+			if got := levels(s.Bids); got != tt.bids {
+				t.Errorf("bids = %s, want %s", got, tt.bids)
+			}
+			if got := levels(s.Asks); got != tt.asks {
+				t.Errorf("asks = %s, want %s", got, tt.asks)
+			}
+			if s.Symbol != "AAPL" || !s.Updated.Equal(last) {
+				t.Errorf("snapshot %s updated %s, want AAPL updated %s", s.Symbol, s.Updated, last)
+			}
+		})
+	}
+}
+
+func TestL2Client(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			cmd, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch strings.TrimRight(cmd, "\r\n") {
+			case "mNSDQ":
+				conn.Write([]byte("M,NSDQ,NASDAQ Stock Market,\r\n"))
+			case "wAAPL":
+				conn.Write([]byte("Z,AAPL,NSDQ,170.12,170.14,100,200,09:30:00,2024-03-05,52,09:30:00,T,T,T,\r\n" +
+					"2,AAPL,NSDQ,170.13,170.14,300,200,09:30:01.250,2024-03-05,52,09:30:00,T,T,T,\r\n"))
+			case "rAAPL":
+				// An update that was already in flight when the watch was removed.
+				conn.Write([]byte("2,AAPL,NSDQ,170.15,170.16,100,100,09:30:02,2024-03-05,52,09:30:00,T,T,T,\r\n"))
+			}
+		}
+	}()
+
+	l := &L2Client{TimeZone: "America/New_York"}
+	if _, err := l.Start(context.Background(), ln.Addr().String()); err != nil {
+		t.Fatalf("Start: %s", err)
+	}
+	defer l.Close()
+	l.RequestMarketMaker("NSDQ")
+	select {
+	case m := <-l.MarketMakers:
+		if m.MMID != "NSDQ" || m.Name != "NASDAQ Stock Market" {
+			t.Errorf("market maker = %+v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the market maker")
+	}
+	if err := l.WatchDepth("AAPL"); err != nil {
+		t.Fatalf("WatchDepth: %s", err)
+	}
+	for _, typ := range []string{"Z", "2"} {
+		select {
+		case m := <-l.Depth:
+			if m.Type != typ || m.Symbol != "AAPL" || m.MMID != "NSDQ" || m.MarketMakerTag != "NASDAQ Stock Market" ||
+				m.ConditionCode != "52" || !m.BidInfoValid || !m.AskInfoValid || !m.EndOfMsgGroup {
+				t.Errorf("depth = %+v", m)
+			}
+			if typ == "2" {
+				want := time.Date(2024, time.March, 5, 9, 30, 1, 250e6, l.TimeLoc)
+				if m.Bid != 170.13 || m.BidSize != 300 || !m.BidTime.Equal(want) {
+					t.Errorf("update bid %g x %d at %s, want 170.13 x 300 at %s", m.Bid, m.BidSize, m.BidTime, want)
+				}
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the %s message", typ)
+		}
+	}
+	// The message is applied before it is sent on Depth.
+	if b, ok := l.Book("AAPL"); !ok || levels(b.Bids(0)) != "NSDQ 170.13x300" {
+		t.Errorf("book = %v, %t", b, ok)
+	}
+
+	if err := l.UnwatchDepth("AAPL"); err != nil {
+		t.Fatalf("UnwatchDepth: %s", err)
+	}
+	select {
+	case m := <-l.Depth:
+		if m.Bid != 170.15 {
+			t.Errorf("depth = %+v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the late update")
+	}
+	if b, ok := l.Book("AAPL"); ok {
+		t.Errorf("the late update recreated the book: %s", levels(b.Bids(0)))
+	}
+
+	l.Close()
+	if err := l.WatchDepth("MSFT"); err == nil {
+		t.Error("WatchDepth on a closed connection returned no error")
+	}
+	if _, ok := l.Book("MSFT"); ok {
+		t.Error("the book of a failed watch was kept")
+	}
+}
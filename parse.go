@@ -0,0 +1,321 @@
+package iqfeed
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errMissingField is the ParseError cause for a line that ends before the field.
+var errMissingField = errors.New("missing field")
+
+// fieldParser converts the fields of a single message, blank fields are returned as zero values as IQFeed leaves unset
+// fields empty. The first missing or invalid field is recorded so UnMarshall can convert every field and check once.
+type fieldParser struct {
+	msg   string   // The message type reported in errors (ex: FundamentalMsg)
+	raw   string   // The raw line reported in errors
+	items []string // The positional fields of the line
+	line  []byte   // The raw line of parsers reading bytes, only copied to raw for an error (the items are joined without it).
+	strs  *stringCache
+	loc   *time.Location
+	err   *ParseError
+	set   [2]uint64 // The positions below 128 that were read and not blank.
+}
+
+func newFieldParser(msg, raw string, items []string, loc *time.Location) *fieldParser {
+	return &fieldParser{msg: msg, raw: raw, items: items, loc: loc}
+}
+
+func (p *fieldParser) fail(field, value string, err error) {
+	if p.err == nil {
+		raw := p.raw
+		switch {
+		case raw != "":
+		case p.line != nil:
+			raw = string(p.line)
+		default:
+			raw = strings.Join(p.items, ",")
+		}
+		p.err = &ParseError{Message: p.msg, Field: field, Value: value, Raw: raw, Err: err}
+	}
+}
+
+// Error returns the first error recorded, it returns a nil error rather than a nil *ParseError.
+func (p *fieldParser) error() error {
+	if p.err == nil {
+		return nil
+	}
+	return p.err
+}
+
+// Str returns the field at position i.
+func (p *fieldParser) str(i int, field string) string {
+	if i >= len(p.items) {
+		p.fail(field, "", errMissingField)
+		return ""
+	}
+	if i < 128 && p.items[i] != "" {
+		p.set[i/64] |= 1 << (i % 64)
+	}
+	return p.items[i]
+}
+
+// OptStr returns the field at position i, or an empty string when the line ends before it, for trailing fields that are
+// not always sent.
+func (p *fieldParser) optStr(i int, field string) string {
+	if i >= len(p.items) {
+		return ""
+	}
+	return p.str(i, field)
+}
+
+// Rest returns the fields from position i to the end of the line, for a last field that may itself contain commas (ex: a
+// description).
+func (p *fieldParser) rest(i int, field string) string {
+	v := p.str(i, field)
+	if i+1 < len(p.items) {
+		v = strings.Join(p.items[i:], ",")
+	}
+	return v
+}
+
+func (p *fieldParser) float(i int, field string) float64 {
+	return p.toFloat(field, p.str(i, field))
+}
+
+func (p *fieldParser) int(i int, field string) int {
+	return p.toInt(field, p.str(i, field))
+}
+
+func (p *fieldParser) time(i int, field, layout string) time.Time {
+	return p.toTime(field, p.str(i, field), layout)
+}
+
+// DateTime parses the CCYY-MM-DD HH:MM:SS layout used by the lookup port, including any fractional seconds (ex:
+// 2016-01-04 09:30:00.123456), date only values (ex: 2016-01-04) are also accepted.
+func (p *fieldParser) dateTime(i int, field string) time.Time {
+	v := p.str(i, field)
+	layout := "2006-01-02 15:04:05"
+	if len(v) == len("2006-01-02") {
+		layout = "2006-01-02"
+	}
+	return p.toTime(field, v, layout)
+}
+
+func (p *fieldParser) toFloat(field, v string) float64 {
+	if v == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		p.fail(field, v, err)
+	}
+	return f
+}
+
+func (p *fieldParser) toInt(field, v string) int {
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		p.fail(field, v, err)
+	}
+	return n
+}
+
+// ToTime parses v with layout in the parser's location, fractional seconds are accepted after the seconds of any layout.
+func (p *fieldParser) toTime(field, v, layout string) time.Time {
+	if v == "" {
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation(layout, strings.TrimSpace(v), p.loc)
+	if err != nil {
+		p.fail(field, v, err)
+	}
+	return t
+}
+
+// The bytes conversions parse the fields of a line in place, they are used on the update path which must not allocate.
+
+// BytesStr returns v as a string, shared through the parser's cache when it has one.
+func (p *fieldParser) bytesStr(v []byte) string {
+	if p.strs == nil {
+		return string(v)
+	}
+	return p.strs.get(v)
+}
+
+func (p *fieldParser) bytesFloat(field string, v []byte) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	f, err := strconv.ParseFloat(string(bytes.TrimSpace(v)), 64)
+	if err != nil {
+		p.fail(field, string(v), err)
+	}
+	return f
+}
+
+func (p *fieldParser) bytesInt(field string, v []byte) int {
+	if len(v) == 0 {
+		return 0
+	}
+	n, err := strconv.Atoi(string(bytes.TrimSpace(v)))
+	if err != nil {
+		p.fail(field, string(v), err)
+	}
+	return n
+}
+
+func (p *fieldParser) bytesPrice(field string, v []byte) Price {
+	d, err := ParsePrice(string(v))
+	if err != nil {
+		p.fail(field, string(v), err)
+	}
+	return d
+}
+
+// BytesTime parses the HH:MM:SS(.fff) and MM/DD/CCYY layouts directly, anything else is left to toTime.
+func (p *fieldParser) bytesTime(field string, v []byte, layout string) time.Time {
+	if len(v) == 0 {
+		return time.Time{}
+	}
+	switch layout {
+	case layoutHMS:
+		if t, ok := parseHMS(v, p.loc); ok {
+			return t
+		}
+	case layoutMMDDCCYY:
+		if t, ok := parseMMDDCCYY(v, p.loc); ok {
+			return t
+		}
+	}
+	return p.toTime(field, string(v), layout)
+}
+
+// parseHMS parses HH:MM:SS with up to nine fractional digits as time.ParseInLocation does for layoutHMS.
+func parseHMS(v []byte, loc *time.Location) (time.Time, bool) {
+	if len(v) < 8 || v[2] != ':' || v[5] != ':' {
+		return time.Time{}, false
+	}
+	h, ok1 := digits(v[0:2])
+	m, ok2 := digits(v[3:5])
+	s, ok3 := digits(v[6:8])
+	if !ok1 || !ok2 || !ok3 || h > 23 || m > 59 || s > 59 {
+		return time.Time{}, false
+	}
+	ns := 0
+	if frac := v[8:]; len(frac) > 0 {
+		if frac[0] != '.' || len(frac) < 2 || len(frac) > 10 {
+			return time.Time{}, false
+		}
+		n, ok := digits(frac[1:])
+		if !ok {
+			return time.Time{}, false
+		}
+		for i := len(frac) - 1; i < 9; i++ {
+			n *= 10
+		}
+		ns = n
+	}
+	return time.Date(0, time.January, 1, h, m, s, ns, loc), true
+}
+
+// parseMMDDCCYY parses MM/DD/CCYY as time.ParseInLocation does for layoutMMDDCCYY.
+func parseMMDDCCYY(v []byte, loc *time.Location) (time.Time, bool) {
+	if len(v) != 10 || v[2] != '/' || v[5] != '/' {
+		return time.Time{}, false
+	}
+	m, ok1 := digits(v[0:2])
+	d, ok2 := digits(v[3:5])
+	y, ok3 := digits(v[6:10])
+	if !ok1 || !ok2 || !ok3 || m < 1 || m > 12 || d < 1 {
+		return time.Time{}, false
+	}
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, loc)
+	if t.Day() != d {
+		// The day is past the end of the month.
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// digits returns the value of the decimal digits in v.
+func digits(v []byte) (int, bool) {
+	n := 0
+	for _, c := range v {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
+
+// nthField returns the field at position i of the comma separated line, it is empty when the line is shorter.
+func nthField(d []byte, i int) []byte {
+	for ; i > 0; i-- {
+		next := bytes.IndexByte(d, ',')
+		if next < 0 {
+			return nil
+		}
+		d = d[next+1:]
+	}
+	if end := bytes.IndexByte(d, ','); end >= 0 {
+		return d[:end]
+	}
+	return d
+}
+
+// maxCachedStrings bounds a stringCache, it is emptied once full rather than growing with every value seen.
+const maxCachedStrings = 8192
+
+// stringCache shares the strings of repeated field values (ex: symbols, market centers and conditions) so they are
+// only allocated once, it is not safe for concurrent use.
+type stringCache struct {
+	m map[string]string
+}
+
+func (s *stringCache) get(v []byte) string {
+	if str, ok := s.m[string(v)]; ok {
+		return str
+	}
+	if s.m == nil || len(s.m) >= maxCachedStrings {
+		s.m = make(map[string]string)
+	}
+	str := string(v)
+	s.m[str] = str
+	return str
+}
+
+// reportParseError sends err on ch without blocking the reader, when nobody drains ch the error is logged and dropped.
+func reportParseError(ch chan *ParseError, err error) {
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		perr = &ParseError{Err: err}
+	}
+	select {
+	case ch <- perr:
+	default:
+		log.Println(perr)
+	}
+}
+
+// withRaw sets the raw line of a *ParseError returned for the fields that follow the message name, so the whole message
+// is reported.
+func withRaw(err error, line []byte) error {
+	if perr, ok := err.(*ParseError); ok {
+		perr.Raw = string(line)
+	}
+	return err
+}
+
+// The layouts IQFeed uses for dates and times.
+const (
+	layoutHMS      = "15:04:05"
+	layoutMMDDCCYY = "01/02/2006"
+)
@@ -0,0 +1,280 @@
+package iqfeed
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHandshakeTimeout is how long we wait for IQConnect to reply to a command we need an answer for.
+const defaultHandshakeTimeout = 5 * time.Second
+
+// ProtocolVersion is an IQFeed protocol version (ex: 6.2), protocols change the message layouts and are selected per connection.
+// See: http://www.iqfeed.net/dev/api/docs/IQFeedVersionHistory.cfm.
+type ProtocolVersion struct {
+	Major int
+	Minor int
+}
+
+// ParseProtocolVersion parses a version in the [Major].[Minor] layout used by S,SET PROTOCOL (ex: 6.2).
+func ParseProtocolVersion(s string) (ProtocolVersion, error) {
+	major, minor, ok := strings.Cut(strings.TrimSpace(s), ".")
+	v := ProtocolVersion{}
+	var err error
+	if v.Major, err = strconv.Atoi(major); err != nil || !ok {
+		return ProtocolVersion{}, fmt.Errorf("iqfeed: invalid protocol version %q", s)
+	}
+	if v.Minor, err = strconv.Atoi(minor); err != nil {
+		return ProtocolVersion{}, fmt.Errorf("iqfeed: invalid protocol version %q", s)
+	}
+	return v, nil
+}
+
+func (v ProtocolVersion) String() string {
+	return strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor)
+}
+
+// AtLeast reports whether v is the given version or newer.
+func (v ProtocolVersion) AtLeast(major, minor int) bool {
+	return v.Major > major || (v.Major == major && v.Minor >= minor)
+}
+
+// updateFieldName returns the name UpdSummaryMsg.UnMarshall expects for a field sent under protocol v. Protocols before
+// 6.0 send the millisecond times as separate "TimeMS" fields, 6.0 removed them and sends every time with microseconds in
+// the plain "Time" fields, so the older names are mapped onto the newer ones.
+func updateFieldName(name string, v ProtocolVersion) string {
+	if !v.AtLeast(6, 0) && strings.HasSuffix(name, " TimeMS") {
+		return strings.TrimSuffix(name, "MS")
+	}
+	return name
+}
+
+// sysReply is the reply delivered to a sysWaiter, either the system message it waits for or the error IQConnect sent instead.
+type sysReply struct {
+	msg *SystemMessage
+	err *ErrorMsg
+}
+
+// sysWaiter waits for the next system message of a single type.
+type sysWaiter struct {
+	typ SystemMsgType
+	cmd string // The command the reply is for, see rejectedBy.
+	ch  chan sysReply
+}
+
+// RejectedBy reports whether e is IQConnect rejecting the command of the waiter. E messages do not say which command they
+// answer, so only messages naming the command or one of its arguments (ex: an invalid field name) are taken as its reply.
+func (w *sysWaiter) rejectedBy(e *ErrorMsg) bool {
+	msg := strings.ToUpper(e.Message)
+	for _, item := range strings.Split(strings.TrimPrefix(w.cmd, "S,"), ",") {
+		if item = strings.ToUpper(strings.TrimSpace(item)); item != "" && strings.Contains(msg, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expect registers a waiter for the next system message of typ in reply to cmd, it must be registered before the command
+// is written so the reply cannot be missed.
+func (c *IQC) expect(typ SystemMsgType, cmd string) *sysWaiter {
+	w := &sysWaiter{typ: typ, cmd: cmd, ch: make(chan sysReply, 1)}
+	c.waitMu.Lock()
+	c.waiters = append(c.waiters, w)
+	c.waitMu.Unlock()
+	return w
+}
+
+// Unexpect removes the waiter, it is safe to call once the reply has been delivered.
+func (c *IQC) unexpect(w *sysWaiter) {
+	c.waitMu.Lock()
+	defer c.waitMu.Unlock()
+	for i, o := range c.waiters {
+		if o == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// DeliverSys hands s to the oldest waiter for its type, it returns false when nothing was waiting for it.
+func (c *IQC) deliverSys(s *SystemMessage) bool {
+	c.waitMu.Lock()
+	defer c.waitMu.Unlock()
+	for i, w := range c.waiters {
+		if w.typ == s.Type {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			w.ch <- sysReply{msg: s}
+			return true
+		}
+	}
+	return false
+}
+
+// DeliverError hands an error message to the oldest waiter whose command it rejects, as IQConnect answers a rejected
+// command with an E message. It returns false for errors unrelated to the pending commands so they reach Errors.
+func (c *IQC) deliverError(e *ErrorMsg) bool {
+	c.waitMu.Lock()
+	defer c.waitMu.Unlock()
+	for i, w := range c.waiters {
+		if w.rejectedBy(e) {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			w.ch <- sysReply{err: e}
+			return true
+		}
+	}
+	return false
+}
+
+func (c *IQC) handshakeTimeout() time.Duration {
+	if c.HandshakeTimeout <= 0 {
+		return defaultHandshakeTimeout
+	}
+	return c.HandshakeTimeout
+}
+
+// Await waits for the reply registered with expect, giving up after HandshakeTimeout.
+func (c *IQC) await(ctx context.Context, w *sysWaiter) (*SystemMessage, error) {
+	defer c.unexpect(w)
+	timeout := c.handshakeTimeout()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-w.ch:
+		if r.err != nil {
+			return nil, &ReplyError{Command: w.cmd, Message: r.err.Message}
+		}
+		return r.msg, nil
+	case <-timer.C:
+		return nil, &ReplyError{Command: w.cmd, Message: "no reply after " + timeout.String()}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.ctx.Done():
+		return nil, errNotConnected
+	}
+}
+
+// Request writes cmd and waits for the system message of typ IQConnect replies to it with.
+func (c *IQC) request(ctx context.Context, typ SystemMsgType, cmd string) (*SystemMessage, error) {
+	w := c.expect(typ, cmd)
+	if err := c.write(cmd + "\r\n"); err != nil {
+		c.unexpect(w)
+		return nil, err
+	}
+	return c.await(ctx, w)
+}
+
+// NegotiateProtocol sets the protocol of the connection and waits for IQConnect to acknowledge it, a *ProtocolError is
+// returned when IQConnect replies with a different protocol. Messages are parsed with the layout of the acknowledged protocol.
+func (c *IQC) NegotiateProtocol(ctx context.Context, protocol string) (ProtocolVersion, error) {
+	want, err := ParseProtocolVersion(protocol)
+	if err != nil {
+		return ProtocolVersion{}, err
+	}
+	if c.ctx == nil {
+		return ProtocolVersion{}, errNotConnected
+	}
+	c.state.setProtocol(want.String())
+	s, err := c.request(ctx, SysCurrentProtocol, "S,SET PROTOCOL,"+want.String())
+	if err != nil {
+		return ProtocolVersion{}, err
+	}
+	got, err := ParseProtocolVersion(s.Protocol)
+	if err != nil || got != want {
+		return got, &ProtocolError{Requested: want.String(), Current: s.Protocol}
+	}
+	return got, nil
+}
+
+// Handshake sets the protocol of a new connection before the reader takes it over and verifies that IQConnect acknowledged
+// it, as the reader that would otherwise receive the acknowledgement is either not started yet or the one reconnecting.
+// The lines received ahead of the acknowledgement are held for the reader so none are lost. Nothing is sent when protocol
+// is empty.
+func (c *IQC) handshake(conn net.Conn, protocol string) (*feedConn, error) {
+	fc := &feedConn{r: bufio.NewReader(conn)}
+	if protocol == "" {
+		return fc, nil
+	}
+	cmd := "S,SET PROTOCOL," + protocol
+	want, err := ParseProtocolVersion(protocol)
+	if err != nil {
+		return nil, &HandshakeError{Command: cmd, Err: err}
+	}
+	cmd = "S,SET PROTOCOL," + want.String()
+	if _, err := conn.Write([]byte(cmd + "\r\n")); err != nil {
+		return nil, &HandshakeError{Command: cmd, Err: err}
+	}
+	// Closing the client must not wait for the deadline.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	timeout := c.handshakeTimeout()
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		line, isPrefix, err := fc.r.ReadLine()
+		switch {
+		case c.ctx.Err() != nil:
+			return nil, &HandshakeError{Command: cmd, Err: c.ctx.Err()}
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			return nil, &HandshakeError{Command: cmd, Err: &ReplyError{Command: cmd, Message: "no reply after " + timeout.String()}}
+		case err != nil:
+			return nil, &HandshakeError{Command: cmd, Err: err}
+		case isPrefix:
+			log.Println("buffer size to small")
+			continue
+		}
+		current, ok := bytes.CutPrefix(line, []byte("S,CURRENT PROTOCOL,"))
+		if !ok {
+			fc.held = append(fc.held, append([]byte(nil), line...))
+			continue
+		}
+		v := string(bytes.TrimSuffix(current, []byte(",")))
+		if got, err := ParseProtocolVersion(v); err != nil || got != want {
+			return nil, &HandshakeError{Command: cmd, Err: &ProtocolError{Requested: want.String(), Current: v}}
+		}
+		c.setVersion(v)
+		return fc, nil
+	}
+}
+
+// ProtocolVersion returns the protocol last acknowledged by IQConnect, it is the zero version until a protocol has been set.
+func (c *IQC) ProtocolVersion() ProtocolVersion {
+	if v := c.version.Load(); v != nil {
+		return *v
+	}
+	return ProtocolVersion{}
+}
+
+// SetVersion records the protocol acknowledged by IQConnect and remaps the update fields to it.
+func (c *IQC) setVersion(protocol string) {
+	v, err := ParseProtocolVersion(protocol)
+	if err != nil {
+		return
+	}
+	c.version.Store(&v)
+	c.mapUpdateFields()
+}
+
+// MapUpdateFields rebuilds the columns the update parser uses from DynFields for the current protocol.
+func (c *IQC) mapUpdateFields() {
+	v := c.ProtocolVersion()
+	fields := make(map[int]string, len(c.DynFields))
+	for i, name := range c.DynFields {
+		fields[i] = updateFieldName(name, v)
+	}
+	c.updColumns = newUpdColumns(fields)
+}
@@ -0,0 +1,214 @@
+package iqfeed
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	minBackoff     = 500 * time.Millisecond
+	defaultBackoff = 30 * time.Second
+)
+
+// ReconnectMsg is sent on IQC.Reconnects after the client has re-established its connection to IQConnect.
+// Any data that IQFeed sent while we were disconnected is lost, so consumers should treat this as a possible gap.
+type ReconnectMsg struct {
+	Cause    error     // The error that dropped the previous connection
+	Attempts int       // The number of dial attempts it took to reconnect
+	Down     time.Time // The time the connection was lost
+	Up       time.Time // The time the connection was restored
+}
+
+// session keeps track of the commands that change the state of a Level 1 connection so they can be replayed after a reconnect.
+type session struct {
+	mu           sync.Mutex
+	protocol     string
+	clientName   string
+	updateFields []string
+	watches      map[string]byte // Symbol to watch command, either 'w' for a full watch or 't' for trades only.
+	regions      map[string]bool
+	news         bool
+}
+
+func (s *session) setProtocol(protocol string) {
+	s.mu.Lock()
+	s.protocol = protocol
+	s.mu.Unlock()
+}
+
+func (s *session) setClientName(name string) {
+	s.mu.Lock()
+	s.clientName = name
+	s.mu.Unlock()
+}
+
+func (s *session) setUpdateFields(fields []string) {
+	s.mu.Lock()
+	s.updateFields = append([]string(nil), fields...)
+	s.mu.Unlock()
+}
+
+func (s *session) watch(symbol string, cmd byte) {
+	s.mu.Lock()
+	if s.watches == nil {
+		s.watches = make(map[string]byte)
+	}
+	s.watches[symbol] = cmd
+	s.mu.Unlock()
+}
+
+func (s *session) unwatch(symbol string) {
+	s.mu.Lock()
+	delete(s.watches, symbol)
+	s.mu.Unlock()
+}
+
+func (s *session) unwatchAll() {
+	s.mu.Lock()
+	s.watches = nil
+	s.mu.Unlock()
+}
+
+func (s *session) setRegion(symbol string, on bool) {
+	s.mu.Lock()
+	if s.regions == nil {
+		s.regions = make(map[string]bool)
+	}
+	if on {
+		s.regions[symbol] = true
+	} else {
+		delete(s.regions, symbol)
+	}
+	s.mu.Unlock()
+}
+
+func (s *session) setNews(on bool) {
+	s.mu.Lock()
+	s.news = on
+	s.mu.Unlock()
+}
+
+func (s *session) currentProtocol() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.protocol
+}
+
+// Commands returns the commands required to bring a fresh connection back to the recorded state, in the order they must be
+// sent. The protocol is not part of them as it is set and verified by the handshake first, see IQC.handshake.
+func (s *session) commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var cmds []string
+	if s.clientName != "" {
+		cmds = append(cmds, "S,SET CLIENT NAME,"+s.clientName+"\r\n")
+	}
+	if len(s.updateFields) > 0 {
+		cmds = append(cmds, "S,SELECT UPDATE FIELDS,"+strings.Join(s.updateFields, ",")+"\r\n")
+	}
+	cmds = append(cmds, "S,REQUEST CURRENT UPDATE FIELDNAMES\r\n")
+	for _, sym := range sortedKeys(s.watches) {
+		cmds = append(cmds, string(s.watches[sym])+sym+"\r\n")
+	}
+	for _, sym := range sortedKeys(s.regions) {
+		cmds = append(cmds, "S,REGON,"+sym+"\r\n")
+	}
+	if s.news {
+		cmds = append(cmds, "S,NEWSON\r\n")
+	}
+	return cmds
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// feedConn is the reader over a connection to IQConnect, together with the lines the handshake read ahead of the protocol
+// acknowledgement which the reader processes first.
+type feedConn struct {
+	r    *bufio.Reader
+	held [][]byte
+}
+
+func (c *IQC) conn() net.Conn {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
+	return c.Conn
+}
+
+func (c *IQC) setConn(conn net.Conn) {
+	c.connMu.Lock()
+	c.Conn = conn
+	c.connMu.Unlock()
+}
+
+// Reconnect dials IQConnect with exponential backoff until a connection is made and the session state is restored,
+// it returns nil if the client was closed before that happened.
+func (c *IQC) reconnect(cause error) *feedConn {
+	down := time.Now()
+	log.Printf("Connection to IQFeed lost (%v), reconnecting...", cause)
+	c.conn().Close()
+	maxBackoff := c.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultBackoff
+	}
+	backoff := minBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(backoff):
+		case <-c.ctx.Done():
+			return nil
+		}
+		fc, err := c.redial()
+		if err == nil {
+			msg := &ReconnectMsg{Cause: cause, Attempts: attempt, Down: down.In(c.TimeLoc), Up: time.Now().In(c.TimeLoc)}
+			select {
+			case c.Reconnects <- msg:
+			default:
+				// Nobody is reading the events, they must not stall the feed.
+				log.Printf("Reconnects is full, dropping %+v", msg)
+			}
+			return fc
+		}
+		log.Printf("Reconnect attempt %d failed: %s", attempt, err)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// Redial opens a new connection, sets and verifies the protocol and replays the session commands on it.
+func (c *IQC) redial() (*feedConn, error) {
+	conn, err := net.Dial("tcp", c.addr)
+	if err != nil {
+		return nil, &DialError{Addr: c.addr, Err: err}
+	}
+	fc, err := c.handshake(conn, c.state.currentProtocol())
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	for _, cmd := range c.state.commands() {
+		if _, err := conn.Write([]byte(cmd)); err != nil {
+			conn.Close()
+			return nil, &HandshakeError{Command: strings.TrimSpace(cmd), Err: err}
+		}
+	}
+	c.setConn(conn)
+	if c.ctx.Err() != nil {
+		// We were closed while dialing, make sure the reader does not block on the new connection.
+		conn.Close()
+	}
+	return fc, nil
+}
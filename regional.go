@@ -18,20 +18,63 @@ type RegionalMsg struct {
 	FractionDispCode int       // Display formatting code see Price Format Codes (http://www.iqfeed.net/dev/api/docs/PriceFormatCodes.cfm).
 	DecPrecision     int       // Last Precision used.
 	MarketCenter     int       // The regional exchange that the updae occurred at. See the Listed Markets Codes for a list of possible values.(http://www.iqfeed.net/dev/api/docs/ListedMarkets.cfm).
+	DecimalPrices    bool      // Keeps the exact bid and ask when it is set before UnMarshall, see Decimal.
+	decimals         [2]Price  // The exact bid and ask, see Decimal.
+	decimalSet       uint8     // The positions of decimals that were sent.
 }
 
-// UnMarshall sends the data into the usable struct for consumption by the application.
-func (r *RegionalMsg) UnMarshall(d []byte, loc *time.Location) {
-	items := strings.Split(string(d), ",")
-	r.Symbol = items[0]
-	r.Exchange = items[1]
-	r.RegBid = GetFloatFromStr(items[2])
-	r.RegBidSize = GetIntFromStr(items[3])
-	r.RegBidTime = GetTimeInHMS(items[4], loc)
-	r.RegAsk = GetFloatFromStr(items[5])
-	r.RegAskSize = GetIntFromStr(items[6])
-	r.RegAskTime = GetTimeInHMS(items[7], loc)
-	r.FractionDispCode = GetIntFromStr(items[8])
-	r.DecPrecision = GetIntFromStr(items[9])
-	r.MarketCenter = GetIntFromStr(items[10])
+// RegionalField identifies a price field of the regional message by its position on the wire, see RegionalMsg.Decimal.
+type RegionalField uint8
+
+// The regional fields holding prices.
+const (
+	RegFieldBid RegionalField = 2
+	RegFieldAsk RegionalField = 5
+)
+
+// Decimal returns the exact price sent for the field, it is only available when DecimalPrices was set.
+func (r *RegionalMsg) Decimal(field RegionalField) (Price, bool) {
+	switch field {
+	case RegFieldBid:
+		return r.decimals[0], r.decimalSet&1 != 0
+	case RegFieldAsk:
+		return r.decimals[1], r.decimalSet&(1<<1) != 0
+	}
+	return Price{}, false
+}
+
+// UnMarshall sends the data into the usable struct for consumption by the application, a *ParseError is returned when
+// the line is too short or a field is invalid.
+func (r *RegionalMsg) UnMarshall(d []byte, loc *time.Location) error {
+	p := newFieldParser("RegionalMsg", string(d), strings.Split(string(d), ","), loc)
+	r.Symbol = p.str(0, "Symbol")
+	r.Exchange = p.str(1, "Exchange")
+	r.RegBid = p.float(2, "RegBid")
+	r.RegBidSize = p.int(3, "RegBidSize")
+	r.RegBidTime = p.time(4, "RegBidTime", layoutHMS)
+	r.RegAsk = p.float(5, "RegAsk")
+	r.RegAskSize = p.int(6, "RegAskSize")
+	r.RegAskTime = p.time(7, "RegAskTime", layoutHMS)
+	r.FractionDispCode = p.int(8, "FractionDispCode")
+	r.DecPrecision = p.int(9, "DecPrecision")
+	r.MarketCenter = p.int(10, "MarketCenter")
+	if !r.DecimalPrices {
+		return p.error()
+	}
+	if v := p.str(int(RegFieldBid), "RegBid"); v != "" {
+		r.decimals[0] = p.toPrice("RegBid", v, r.DecPrecision)
+		r.decimalSet |= 1
+	}
+	if v := p.str(int(RegFieldAsk), "RegAsk"); v != "" {
+		r.decimals[1] = p.toPrice("RegAsk", v, r.DecPrecision)
+		r.decimalSet |= 1 << 1
+	}
+	return p.error()
+}
+
+// AttachDate turns the time-only bid and ask times into full instants on the date of the feed time ref, which is the
+// latest T message for messages received from IQC.
+func (r *RegionalMsg) AttachDate(ref time.Time) {
+	r.RegBidTime = attachFeedDate(ref, r.RegBidTime)
+	r.RegAskTime = attachFeedDate(ref, r.RegAskTime)
 }
@@ -0,0 +1,189 @@
+package iqfeed
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ReplayMode selects how fast a backup file is replayed.
+type ReplayMode int
+
+const (
+	ReplayFast     ReplayMode = iota // Replay every line as fast as the consumers read the channels.
+	ReplayRealTime                   // Pace the replay using the recorded receive times, or the T time messages for files without them.
+)
+
+// ReplayOptions controls how backup files are replayed.
+type ReplayOptions struct {
+	Mode  ReplayMode
+	Speed float64 // Multiplier for ReplayRealTime, 2 replays twice as fast as recorded. Defaults to 1.
+	// Decompressor reads files written with a custom CaptureWriter.Compressor (ex: zstd), only gzip is detected automatically
+	// as the package has no dependencies outside the standard library.
+	Decompressor func(r io.Reader) (io.Reader, error)
+}
+
+// StartReplay reads the files recorded with CreateBackup or a CaptureWriter and feeds every line through the same parser and
+// channels as a live connection, so a recorded session can be processed exactly like the original one. backupFile is either
+// a single file or a glob pattern (ex: feed.*.log.gz), the matching files are replayed in name order which is the order the
+// CaptureWriter rotated them in. See StartReplayFiles.
+func (c *IQC) StartReplay(ctx context.Context, backupFile string, opts *ReplayOptions) (*IQC, error) {
+	files, err := filepath.Glob(backupFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		// Not a pattern, or nothing matched it: report the missing file.
+		files = []string{backupFile}
+	}
+	sort.Strings(files)
+	return c.StartReplayFiles(ctx, files, opts)
+}
+
+// StartReplayFiles replays the files one after another as a single session, the output channels are closed once the last
+// file has been replayed or the client is closed. There is no connection to IQConnect, commands are discarded. Real time
+// pacing uses the receive time of each record when the files have them, otherwise the T messages. Every file is checked to
+// exist before the replay starts.
+func (c *IQC) StartReplayFiles(ctx context.Context, files []string, opts *ReplayOptions) (*IQC, error) {
+	var err error
+	c.TimeLoc, err = loadTimeLoc(&c.TimeZone, c.TimeLoc)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("iqfeed: no files to replay")
+	}
+	for _, name := range files {
+		if _, err := os.Stat(name); err != nil {
+			return nil, err
+		}
+	}
+	if opts == nil {
+		opts = &ReplayOptions{}
+	}
+	c.DynFields = make(map[int]string)
+	c.init(ctx)
+	c.wg.Add(1)
+	go c.replay(files, opts)
+	return c, nil
+}
+
+// ReplayReader returns the reader for the records in f, decompressing it when required.
+func replayReader(f *os.File, opts *ReplayOptions) (io.Reader, error) {
+	if opts.Decompressor != nil {
+		return opts.Decompressor(f)
+	}
+	br := bufio.NewReader(f)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// splitRecord splits a CaptureWriter record into its receive time and raw line, lines written without a receive time
+// are returned with a zero time.
+func splitRecord(rec []byte) (time.Time, []byte) {
+	sp := bytes.IndexByte(rec, ' ')
+	if sp < 1 || sp > 19 {
+		return time.Time{}, rec
+	}
+	var ns int64
+	for _, b := range rec[:sp] {
+		if b < '0' || b > '9' {
+			return time.Time{}, rec
+		}
+		ns = ns*10 + int64(b-'0')
+	}
+	return time.Unix(0, ns), rec[sp+1:]
+}
+
+// Replay processes every line of the files in turn, waiting between records when pacing is requested. The pacing carries
+// on across files so a rotated capture replays like the session it was recorded from.
+func (c *IQC) replay(files []string, opts *ReplayOptions) {
+	defer c.wg.Done()
+	defer c.closeChannels()
+	speed := opts.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+	var feedStart time.Time
+	var wallStart time.Time
+	pace := func(t time.Time) bool {
+		if feedStart.IsZero() {
+			feedStart, wallStart = t, time.Now()
+			return true
+		}
+		return c.waitUntil(wallStart.Add(time.Duration(float64(t.Sub(feedStart)) / speed)))
+	}
+	for _, name := range files {
+		if !c.replayFile(name, opts, pace) {
+			return
+		}
+	}
+}
+
+// ReplayFile processes every line of a single file, it returns false when the replay must stop.
+func (c *IQC) replayFile(name string, opts *ReplayOptions, pace func(t time.Time) bool) bool {
+	f, err := os.Open(name)
+	if err != nil {
+		log.Printf("Replay stopped: %s", err)
+		return false
+	}
+	defer f.Close()
+	r, err := replayReader(f, opts)
+	if err != nil {
+		log.Printf("Replay stopped: %s: %s", name, err)
+		return false
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		if c.ctx.Err() != nil {
+			return false
+		}
+		recv, line := splitRecord(bytes.TrimSuffix(sc.Bytes(), []byte("\r")))
+		if opts.Mode == ReplayRealTime {
+			switch {
+			case !recv.IsZero():
+				if !pace(recv) {
+					return false
+				}
+			case len(line) > 2 && line[0] == 'T':
+				t := &TimeMsg{}
+				if t.UnMarshall(line[2:], c.TimeLoc) == nil && !pace(t.TimeStamp) {
+					return false
+				}
+			}
+		}
+		c.processReceiver(line)
+	}
+	if err := sc.Err(); err != nil {
+		log.Printf("Replay stopped: %s: %s", name, err)
+		return false
+	}
+	return true
+}
+
+// WaitUntil sleeps until the wall clock reaches t, it returns false if the client was closed while waiting.
+func (c *IQC) waitUntil(t time.Time) bool {
+	d := time.Until(t)
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,144 @@
+package iqfeed
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// replayTimes replays the lines and returns the wall time each T message was received at, relative to the first one.
+func replayTimes(t *testing.T, lines []string, opts *ReplayOptions) []time.Duration {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "backup.txt")
+	if err := os.WriteFile(name, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := &IQC{TimeZone: "America/New_York"}
+	if _, err := c.StartReplay(context.Background(), name, opts); err != nil {
+		t.Fatalf("StartReplay: %s", err)
+	}
+	defer c.Close()
+	var start time.Time
+	var times []time.Duration
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case _, ok := <-c.Time:
+			if !ok {
+				return times
+			}
+			if start.IsZero() {
+				start = time.Now()
+			}
+			times = append(times, time.Since(start))
+		case <-timeout:
+			t.Fatalf("replay did not finish, received %d time messages", len(times))
+		}
+	}
+}
+
+// checkPacing fails the test unless every message was received at its expected offset, messages may be late by up to slack.
+func checkPacing(t *testing.T, got, want []time.Duration, slack time.Duration) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("received %d time messages, want %d", len(got), len(want))
+	}
+	for i := range want {
+		// The replay takes its start time just before sending the first message, so allow for a little early delivery.
+		if got[i] < want[i]-20*time.Millisecond || got[i] > want[i]+slack {
+			t.Errorf("message %d received after %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReplayFast(t *testing.T) {
+	got := replayTimes(t, []string{"T,20240305 09:30:00", "T,20240305 10:30:00", "T,20240305 11:30:00"}, nil)
+	checkPacing(t, got, []time.Duration{0, 0, 0}, 500*time.Millisecond)
+}
+
+func TestReplayTimeMessages(t *testing.T) {
+	lines := []string{"T,20240305 09:30:00", "T,20240305 09:30:01", "T,20240305 09:30:02"}
+	got := replayTimes(t, lines, &ReplayOptions{Mode: ReplayRealTime, Speed: 4})
+	// Two seconds of feed at four times the recorded speed, unscaled pacing would take far longer than the slack.
+	checkPacing(t, got, []time.Duration{0, 250 * time.Millisecond, 500 * time.Millisecond}, 200*time.Millisecond)
+}
+
+func TestReplayReceiveTimes(t *testing.T) {
+	recv := time.Date(2024, time.March, 5, 9, 30, 0, 0, time.UTC).UnixNano()
+	record := func(offset time.Duration, line string) string {
+		return strconv.FormatInt(recv+int64(offset), 10) + " " + line
+	}
+	// The receive times take precedence over the T messages which are an hour apart.
+	lines := []string{
+		record(0, "T,20240305 09:30:00"),
+		record(150*time.Millisecond, "T,20240305 10:30:00"),
+		record(300*time.Millisecond, "T,20240305 11:30:00"),
+	}
+	got := replayTimes(t, lines, &ReplayOptions{Mode: ReplayRealTime})
+	checkPacing(t, got, []time.Duration{0, 150 * time.Millisecond, 300 * time.Millisecond}, 200*time.Millisecond)
+
+	got = replayTimes(t, lines, &ReplayOptions{Mode: ReplayRealTime, Speed: 3})
+	checkPacing(t, got, []time.Duration{0, 50 * time.Millisecond, 100 * time.Millisecond}, 90*time.Millisecond)
+}
+
+func TestReplayFiles(t *testing.T) {
+	dir := t.TempDir()
+	// Rotated files are named after the time they were opened, the glob must replay them in that order whatever the
+	// order they were created in.
+	files := map[string]string{
+		"feed.20240305-100000.000.log": "T,20240305 10:00:00\r\nT,20240305 10:00:01\r\n",
+		"feed.20240305-093000.000.log": "T,20240305 09:30:00\r\n",
+		"other.log":                    "T,20240305 12:00:00\r\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	replay := func(start func(c *IQC) (*IQC, error)) string {
+		c := &IQC{TimeZone: "America/New_York"}
+		if _, err := start(c); err != nil {
+			t.Fatalf("replay: %s", err)
+		}
+		defer c.Close()
+		var got []string
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case tm, ok := <-c.Time:
+				if !ok {
+					return strings.Join(got, " ")
+				}
+				got = append(got, tm.TimeStamp.Format("15:04:05"))
+			case <-timeout:
+				t.Fatalf("replay did not finish, received %v", got)
+			}
+		}
+	}
+
+	got := replay(func(c *IQC) (*IQC, error) {
+		return c.StartReplay(context.Background(), filepath.Join(dir, "feed.*.log"), nil)
+	})
+	if want := "09:30:00 10:00:00 10:00:01"; got != want {
+		t.Errorf("glob replayed %s, want %s", got, want)
+	}
+	got = replay(func(c *IQC) (*IQC, error) {
+		return c.StartReplayFiles(context.Background(), []string{filepath.Join(dir, "other.log"), filepath.Join(dir, "feed.20240305-093000.000.log")}, nil)
+	})
+	if want := "12:00:00 09:30:00"; got != want {
+		t.Errorf("files replayed %s, want %s", got, want)
+	}
+
+	c := &IQC{TimeZone: "America/New_York"}
+	if _, err := c.StartReplay(context.Background(), filepath.Join(dir, "missing.log"), nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("StartReplay of a missing file = %v, want os.ErrNotExist", err)
+	}
+	if _, err := c.StartReplayFiles(context.Background(), []string{filepath.Join(dir, "other.log"), filepath.Join(dir, "missing.log")}, nil); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("StartReplayFiles with a missing file = %v, want os.ErrNotExist", err)
+	}
+}
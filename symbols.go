@@ -0,0 +1,116 @@
+package iqfeed
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SearchField selects what a SymbolSearch matches the search text against.
+type SearchField string
+
+const (
+	SearchSymbols      SearchField = "s" // Match the start of the symbol.
+	SearchDescriptions SearchField = "d" // Match any part of the description.
+)
+
+// SymbolSearch describes a symbol search (SBF), results can be limited to listed markets or security types but not both.
+type SymbolSearch struct {
+	Field         SearchField // Defaults to SearchSymbols.
+	Text          string      // The text to search for.
+	ListedMarkets []int       // Only return symbols listed on these markets, see ListedMarkets.
+	SecurityTypes []int       // Only return symbols of these security types, see SecurityTypes.
+}
+
+func (s *SymbolSearch) command(id string) string {
+	field := s.Field
+	if field == "" {
+		field = SearchSymbols
+	}
+	var filterType string
+	var filter []int
+	switch {
+	case len(s.ListedMarkets) > 0:
+		filterType, filter = "e", s.ListedMarkets
+	case len(s.SecurityTypes) > 0:
+		filterType, filter = "t", s.SecurityTypes
+	}
+	vals := make([]string, len(filter))
+	for i, v := range filter {
+		vals[i] = strconv.Itoa(v)
+	}
+	return fmt.Sprintf("SBF,%s,%s,%s,%s,%s\r\n", field, s.Text, filterType, strings.Join(vals, " "), id)
+}
+
+// SymbolMatch is a single symbol returned by a symbol search.
+type SymbolMatch struct {
+	Symbol       string // The symbol as used in watch requests
+	ListedMarket int    // The listing market ID, see ListedMarkets
+	SecurityType int    // The security type ID, see SecurityTypes
+	Description  string // Company name or contract description
+	SIC          int    // Set for searches by SIC code
+	NAICS        int    // Set for searches by NAICS code
+}
+
+// UnMarshall sends the SBF data into the usable struct for consumption by the application, a *ParseError is returned
+// when a field is missing or invalid.
+func (m *SymbolMatch) UnMarshall(items []string) error {
+	p := newFieldParser("SymbolMatch", strings.Join(items, ","), items, nil)
+	m.unMarshall(p, 0)
+	return p.error()
+}
+
+// unMarshall reads the SBF fields starting at position i, the SBS and SBN rows send them after the industry code.
+func (m *SymbolMatch) unMarshall(p *fieldParser, i int) {
+	m.Symbol = p.str(i, "Symbol")
+	m.ListedMarket = p.int(i+1, "ListedMarket")
+	m.SecurityType = p.int(i+2, "SecurityType")
+	m.Description = p.rest(i+3, "Description")
+}
+
+// SearchSymbols returns every symbol matching the search.
+func (l *LookupClient) SearchSymbols(ctx context.Context, s *SymbolSearch) ([]*SymbolMatch, error) {
+	var matches []*SymbolMatch
+	err := l.request(ctx, s.command, func(items []string) error {
+		m := &SymbolMatch{}
+		if err := m.UnMarshall(items); err != nil {
+			return err
+		}
+		matches = append(matches, m)
+		return nil
+	})
+	return matches, err
+}
+
+// SearchBySIC returns every symbol whose SIC code starts with the prefix (SBS).
+func (l *LookupClient) SearchBySIC(ctx context.Context, prefix string) ([]*SymbolMatch, error) {
+	var matches []*SymbolMatch
+	err := l.request(ctx, func(id string) string { return "SBS," + prefix + "," + id + "\r\n" }, func(items []string) error {
+		p := newFieldParser("SymbolMatch", strings.Join(items, ","), items, nil)
+		m := &SymbolMatch{SIC: p.int(0, "SIC")}
+		m.unMarshall(p, 1)
+		if err := p.error(); err != nil {
+			return err
+		}
+		matches = append(matches, m)
+		return nil
+	})
+	return matches, err
+}
+
+// SearchByNAICS returns every symbol whose NAICS code starts with the prefix (SBN).
+func (l *LookupClient) SearchByNAICS(ctx context.Context, prefix string) ([]*SymbolMatch, error) {
+	var matches []*SymbolMatch
+	err := l.request(ctx, func(id string) string { return "SBN," + prefix + "," + id + "\r\n" }, func(items []string) error {
+		p := newFieldParser("SymbolMatch", strings.Join(items, ","), items, nil)
+		m := &SymbolMatch{NAICS: p.int(0, "NAICS")}
+		m.unMarshall(p, 1)
+		if err := p.error(); err != nil {
+			return err
+		}
+		matches = append(matches, m)
+		return nil
+	})
+	return matches, err
+}
@@ -0,0 +1,148 @@
+package iqfeed
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchSymbols(t *testing.T) {
+	l, cmds := startLookup(t, "", func(cmd, id string) []string {
+		switch {
+		case strings.HasPrefix(cmd, "SBF,"):
+			return []string{"{id},AAPL,5,1,APPLE INC,", "{id},AAPD,5,1,DIREXION DAILY AAPL BEAR 1X, SHARES,", "{id},!ENDMSG!,"}
+		case strings.HasPrefix(cmd, "SBS,"):
+			return []string{"{id},3571,AAPL,5,1,APPLE INC,", "{id},!ENDMSG!,"}
+		case strings.HasPrefix(cmd, "SBN,"):
+			return []string{"{id},334111,DELL,7,1,DELL TECHNOLOGIES INC,", "{id},!ENDMSG!,"}
+		}
+		return []string{"{id},E,Unexpected command.,", "{id},!ENDMSG!,"}
+	})
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		search func() ([]*SymbolMatch, error)
+		cmd    string
+		want   []*SymbolMatch
+	}{
+		{"SBF", func() ([]*SymbolMatch, error) {
+			return l.SearchSymbols(ctx, &SymbolSearch{Text: "AAP", SecurityTypes: []int{1, 5}})
+		}, "SBF,s,AAP,t,1 5,L1", []*SymbolMatch{
+			{Symbol: "AAPL", ListedMarket: 5, SecurityType: 1, Description: "APPLE INC"},
+			{Symbol: "AAPD", ListedMarket: 5, SecurityType: 1, Description: "DIREXION DAILY AAPL BEAR 1X, SHARES"},
+		}},
+		{"SBF descriptions", func() ([]*SymbolMatch, error) {
+			return l.SearchSymbols(ctx, &SymbolSearch{Field: SearchDescriptions, Text: "APPLE", ListedMarkets: []int{5}})
+		}, "SBF,d,APPLE,e,5,L2", nil},
+		{"SBS", func() ([]*SymbolMatch, error) { return l.SearchBySIC(ctx, "357") },
+			"SBS,357,L3", []*SymbolMatch{{Symbol: "AAPL", ListedMarket: 5, SecurityType: 1, Description: "APPLE INC", SIC: 3571}}},
+		{"SBN", func() ([]*SymbolMatch, error) { return l.SearchByNAICS(ctx, "3341") },
+			"SBN,3341,L4", []*SymbolMatch{{Symbol: "DELL", ListedMarket: 7, SecurityType: 1, Description: "DELL TECHNOLOGIES INC", NAICS: 334111}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.search()
+			if cmd := <-cmds; cmd != tt.cmd {
+				t.Errorf("command = %q, want %q", cmd, tt.cmd)
+			}
+			if tt.want == nil {
+				// Only the command is checked, the fake returns the same rows for every SBF.
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matches = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarketTables(t *testing.T) {
+	l, cmds := startLookup(t, "6.2", func(cmd, id string) []string {
+		var rows []string
+		switch {
+		case strings.HasPrefix(cmd, "SLM,"):
+			rows = []string{"5,NASDAQ,Nasdaq Global Select Market,5,NASDAQ", "7,NYSE,New York Stock Exchange,7,NYSE"}
+		case strings.HasPrefix(cmd, "SST,"):
+			rows = []string{"1,EQUITY,Equity", "2,IEOPTION,Index/Equity Option"}
+		case strings.HasPrefix(cmd, "STC,"):
+			rows = []string{"1,REGULAR,Normal Trade", "61,FORMT,Form T Trade, extended hours"}
+		case strings.HasPrefix(cmd, "SSC,"):
+			rows = []string{"3571,ELECTRONIC COMPUTERS"}
+		case strings.HasPrefix(cmd, "SNC,"):
+			rows = []string{"334111,Electronic Computer Manufacturing"}
+		}
+		lines := make([]string, 0, len(rows)+1)
+		for _, r := range rows {
+			lines = append(lines, "{id},LS,"+r+",")
+		}
+		return append(lines, "{id},!ENDMSG!,")
+	})
+	ctx := context.Background()
+	t.Cleanup(func() { DefaultTradeConditions.Refresh(builtinTradeConditions) })
+
+	tables, err := l.MarketTables(ctx)
+	if err != nil {
+		t.Fatalf("MarketTables: %s", err)
+	}
+	var sent []string
+	for i := 0; i < 5; i++ {
+		sent = append(sent, <-cmds)
+	}
+	if want := []string{"SLM,L1", "SST,L2", "STC,L3", "SSC,L4", "SNC,L5"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("commands = %q, want %q", sent, want)
+	}
+	if m := tables.ListedMarkets[5]; m == nil || *m != (ListedMarket{ID: 5, ShortName: "NASDAQ", LongName: "Nasdaq Global Select Market", GroupID: 5, GroupName: "NASDAQ"}) {
+		t.Errorf("listed market 5 = %+v", m)
+	}
+	if got := tables.ListedMarketName("7"); got != "NYSE" {
+		t.Errorf("ListedMarketName(7) = %q", got)
+	}
+	if got := tables.ListedMarketName("99"); got != "99" {
+		t.Errorf("ListedMarketName(99) = %q, want the ID back", got)
+	}
+	if got := tables.SecurityTypeName("2"); got != "IEOPTION" {
+		t.Errorf("SecurityTypeName(2) = %q", got)
+	}
+	if tc := tables.TradeConditions[61]; tc == nil || tc.LongName != "Form T Trade, extended hours" {
+		t.Errorf("trade condition 61 = %+v", tc)
+	}
+	if got, want := tables.TradeConditionNames("013DFF"), []string{"REGULAR", "FORMT", "FF"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TradeConditionNames(013DFF) = %q, want %q", got, want)
+	}
+	if c := ParseTradeConditions("3D"); len(c) != 1 || c[0].ShortName != "FORMT" || !c[0].Class.Has(CondExtendedHours) {
+		t.Errorf("DefaultTradeConditions was not refreshed, ParseTradeConditions(3D) = %+v", c)
+	}
+	if got := tables.SICName(3571); got != "ELECTRONIC COMPUTERS" {
+		t.Errorf("SICName(3571) = %q", got)
+	}
+	if got := tables.NAICSName(334111); got != "Electronic Computer Manufacturing" {
+		t.Errorf("NAICSName(334111) = %q", got)
+	}
+
+	// The tables are cached until they are refreshed.
+	if again, err := l.MarketTables(ctx); err != nil || again != tables {
+		t.Errorf("MarketTables did not return the cached tables: %v", err)
+	}
+	if len(cmds) != 0 {
+		t.Errorf("MarketTables sent %q, want the cached tables to be used", <-cmds)
+	}
+	refreshed, err := l.RefreshMarketTables(ctx)
+	if err != nil || refreshed == tables {
+		t.Fatalf("RefreshMarketTables = %p, %v, want new tables", refreshed, err)
+	}
+	if cached, _ := l.MarketTables(ctx); cached != refreshed {
+		t.Error("MarketTables did not return the refreshed tables")
+	}
+}
+
+func TestSymbolMatchErrors(t *testing.T) {
+	var m SymbolMatch
+	err := m.UnMarshall([]string{"AAPL", "NASDAQ", "1", "APPLE INC"})
+	if perr, ok := err.(*ParseError); !ok || perr.Field != "ListedMarket" || perr.Raw != "AAPL,NASDAQ,1,APPLE INC" {
+		t.Errorf("UnMarshall with a market name = %v", err)
+	}
+	if err := m.UnMarshall([]string{"AAPL", "5"}); err == nil {
+		t.Error("UnMarshall of a short row returned no error")
+	}
+}
@@ -1,11 +1,47 @@
 package iqfeed
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
-// SystemMessage is the main system message that will be returned and set by the client.
+// SystemMsgType identifies which system message a SystemMessage holds, it is the message name sent after the S, prefix.
+type SystemMsgType string
+
+// The system message types sent by IQConnect, see: http://www.iqfeed.net/dev/api/docs/Level1SystemMessage.cfm.
+const (
+	SysUnknown                 SystemMsgType = ""
+	SysStats                   SystemMsgType = "STATS"
+	SysCustomer                SystemMsgType = "CUST"
+	SysKey                     SystemMsgType = "KEY"
+	SysKeyOK                   SystemMsgType = "KEYOK"
+	SysIP                      SystemMsgType = "IP"
+	SysServerConnected         SystemMsgType = "SERVER CONNECTED"
+	SysServerDisconnected      SystemMsgType = "SERVER DISCONNECTED"
+	SysServerReconnectFailed   SystemMsgType = "SERVER RECONNECT FAILED"
+	SysSymbolLimitReached      SystemMsgType = "SYMBOL LIMIT REACHED"
+	SysWatches                 SystemMsgType = "WATCHES"
+	SysCurrentProtocol         SystemMsgType = "CURRENT PROTOCOL"
+	SysCurrentLogLevels        SystemMsgType = "CURRENT LOG LEVELS"
+	SysCurrentClientName       SystemMsgType = "CURRENT CLIENT NAME"
+	SysFundamentalFieldNames   SystemMsgType = "FUNDAMENTAL FIELDNAMES"
+	SysUpdateFieldNames        SystemMsgType = "UPDATE FIELDNAMES"
+	SysCurrentUpdateFieldNames SystemMsgType = "CURRENT UPDATE FIELDNAMES"
+)
+
+// SystemMessage is the main system message that will be returned and set by the client, Type tells which of the fields are set.
 type SystemMessage struct {
-	Customer CustomerData
-	Stats    SystemStats
+	Type     SystemMsgType // The message name, SysUnknown for messages this package does not recognise (see Raw).
+	Customer CustomerData  // Set for SysCustomer.
+	Stats    SystemStats   // Set for SysStats.
+	Key      string        // Set for SysKey.
+	IP       string        // Set for SysIP, the IP address and port of the quote server.
+	Symbol   string        // Set for SysSymbolLimitReached, the symbol that could not be watched.
+	Symbols  []string      // Set for SysWatches, the symbols watched on this connection.
+	Protocol string        // Set for SysCurrentProtocol.
+	Name     string        // Set for SysCurrentClientName.
+	Fields   []string      // Set for the field name messages and SysCurrentLogLevels.
+	Raw      string        // The message as received without the S, prefix.
 }
 
 // CustomerData is a subset of SystemMessage which is returned when requesting customer data.
@@ -47,8 +83,110 @@ type SystemStats struct {
 	AvgKBsPerSecSent       float32   // Found in the “Local Bandwidth” section of the IQFeed Connection Manager. Formula: total KB's sent / total seconds
 }
 
-// UnMarshall sends the data into the usable struct for consumption by the application.
-func (f *SystemMessage) UnMarshall(d []byte, loc *time.Location) {
-	//fmt.Printf("System Message: %s", string(d))
-	return
+// UnMarshall sends the data into the usable struct for consumption by the application, a *ParseError is returned when
+// the fields of a S,STATS or S,CUST message are missing or invalid.
+func (f *SystemMessage) UnMarshall(d []byte, loc *time.Location) error {
+	f.Raw = string(d)
+	items := strings.Split(strings.TrimSuffix(f.Raw, ","), ",")
+	f.Type = SystemMsgType(items[0])
+	args := items[1:]
+	var err error
+	switch f.Type {
+	case SysStats:
+		err = f.Stats.UnMarshall(args, loc)
+	case SysCustomer:
+		err = f.Customer.UnMarshall(args)
+	case SysKey:
+		f.Key = strings.Join(args, ",")
+	case SysIP:
+		f.IP = strings.Join(args, ",")
+	case SysSymbolLimitReached:
+		f.Symbol = strings.Join(args, ",")
+	case SysWatches:
+		f.Symbols = args
+	case SysCurrentProtocol:
+		f.Protocol = strings.Join(args, ",")
+	case SysCurrentClientName:
+		f.Name = strings.Join(args, ",")
+	case SysCurrentLogLevels, SysFundamentalFieldNames, SysUpdateFieldNames, SysCurrentUpdateFieldNames:
+		f.Fields = args
+	case SysKeyOK, SysServerConnected, SysServerDisconnected, SysServerReconnectFailed:
+		// These messages carry no data.
+	default:
+		f.Type = SysUnknown
+	}
+	return withRaw(err, d)
+}
+
+// UnMarshall sends the S,CUST fields (starting after the CUST name) into the usable struct for consumption by the application,
+// a *ParseError is returned when a field is missing or invalid.
+func (c *CustomerData) UnMarshall(items []string) error {
+	p := newFieldParser("CustomerData", strings.Join(items, ","), items, nil)
+	c.ServiceType = p.str(0, "ServiceType")
+	c.IP = p.str(1, "IP")
+	c.Port = p.int(2, "Port")
+	c.Token = p.str(3, "Token")
+	c.Version = p.str(4, "Version")
+	c.Deprecated1 = p.int(5, "Deprecated1")
+	c.VerboseExchanges = p.str(6, "VerboseExchanges")
+	c.Deprecated2 = p.str(7, "Deprecated2")
+	c.MaxSymbols = p.int(8, "MaxSymbols")
+	c.Flags = p.str(9, "Flags")
+	c.Deprecated3 = p.optStr(10, "Deprecated3")
+	c.Deprecated4 = p.optStr(11, "Deprecated4")
+	return p.error()
+}
+
+// layoutStatsTime is the [short month][space][Day][space][hour][colon][minute][AM/PM] layout of the S,STATS times.
+const layoutStatsTime = "Jan 2 3:04PM"
+
+// UnMarshall sends the S,STATS fields (starting after the STATS name) into the usable struct for consumption by the application,
+// a *ParseError is returned when a field is missing or invalid.
+func (s *SystemStats) UnMarshall(items []string, loc *time.Location) error {
+	p := newFieldParser("SystemStats", strings.Join(items, ","), items, loc)
+	s.ServerIP = p.str(0, "ServerIP")
+	s.ServerPort = p.int(1, "ServerPort")
+	s.MaxSymbols = p.int(2, "MaxSymbols")
+	s.NumberOfSymbols = p.int(3, "NumberOfSymbols")
+	s.ClientsConnected = p.int(4, "ClientsConnected")
+	s.SecondsSinceLastUpdate = p.int(5, "SecondsSinceLastUpdate")
+	s.Reconnections = p.int(6, "Reconnections")
+	s.AttemptedReconnections = p.int(7, "AttemptedReconnections")
+	// The market time is the latest of the two, it is dated from the clock and the start time from the market time.
+	s.MarketTime = getStatsTime(p.toTime("MarketTime", statsTimeValue(p.str(9, "MarketTime")), layoutStatsTime), time.Now().In(loc))
+	startRef := s.MarketTime
+	if startRef.IsZero() {
+		startRef = time.Now().In(loc)
+	}
+	s.StartTime = getStatsTime(p.toTime("StartTime", statsTimeValue(p.str(8, "StartTime")), layoutStatsTime), startRef)
+	s.Status = p.str(10, "Status")
+	s.IQFeedVersion = p.str(11, "IQFeedVersion")
+	s.LoginID = p.str(12, "LoginID")
+	s.TotalKBsRecv = float32(p.float(13, "TotalKBsRecv"))
+	s.KBsPerSecRecv = float32(p.float(14, "KBsPerSecRecv"))
+	s.AvgKBsPerSecRecv = float32(p.float(15, "AvgKBsPerSecRecv"))
+	s.TotalKBsSent = float32(p.float(16, "TotalKBsSent"))
+	s.KBsPerSecSent = float32(p.float(17, "KBsPerSecSent"))
+	s.AvgKBsPerSecSent = float32(p.float(18, "AvgKBsPerSecSent"))
+	return p.error()
+}
+
+// statsTimeValue collapses the padding IQConnect puts in the S,STATS times (ex: "Mar  5 9:30AM").
+func statsTimeValue(d string) string {
+	return strings.Join(strings.Fields(d), " ")
+}
+
+// getStatsTime adds the year to a time parsed with layoutStatsTime, the layout has no year so it is taken from ref and
+// rolled back a year when the result would be after ref (ex: a Dec 31 start time read on Jan 1). Zero times are returned untouched.
+func getStatsTime(t, ref time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	// The parsed time is in year 0, it is rebuilt rather than shifted so the UTC offset is the one of the actual date.
+	d := time.Date(ref.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, ref.Location())
+	// A day of slack keeps the clock of the caller being slightly behind IQConnect from dating the time a year back.
+	if d.After(ref.AddDate(0, 0, 1)) {
+		d = d.AddDate(-1, 0, 0)
+	}
+	return d
 }
@@ -0,0 +1,109 @@
+package iqfeed
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSystemMessage(t *testing.T) {
+	year := statsYear(time.UTC)
+	tests := []struct {
+		line string
+		want SystemMessage
+	}{
+		{"KEY,12345678,", SystemMessage{Type: SysKey, Key: "12345678"}},
+		{"KEYOK,", SystemMessage{Type: SysKeyOK}},
+		{"SERVER CONNECTED,", SystemMessage{Type: SysServerConnected}},
+		{"SERVER DISCONNECTED,", SystemMessage{Type: SysServerDisconnected}},
+		{"SERVER RECONNECT FAILED,", SystemMessage{Type: SysServerReconnectFailed}},
+		{"WATCHES,AAPL,MSFT,@ES#,", SystemMessage{Type: SysWatches, Symbols: []string{"AAPL", "MSFT", "@ES#"}}},
+		{"WATCHES,", SystemMessage{Type: SysWatches, Symbols: []string{}}},
+		{"SYMBOL LIMIT REACHED,TSLA,", SystemMessage{Type: SysSymbolLimitReached, Symbol: "TSLA"}},
+		{"IP,66.112.148.225 60002,", SystemMessage{Type: SysIP, IP: "66.112.148.225 60002"}},
+		{"CURRENT PROTOCOL,6.2,", SystemMessage{Type: SysCurrentProtocol, Protocol: "6.2"}},
+		{"CURRENT CLIENT NAME,Charts,", SystemMessage{Type: SysCurrentClientName, Name: "Charts"}},
+		{"CURRENT UPDATE FIELDNAMES,Symbol,Most Recent Trade,", SystemMessage{Type: SysCurrentUpdateFieldNames, Fields: []string{"Symbol", "Most Recent Trade"}}},
+		{"CUST,real_time,66.112.148.225,60002,1a2b3c,6.2.0.25,0,NASDAQ NYSE ,,1300,NO_EOD,,,", SystemMessage{Type: SysCustomer,
+			Customer: CustomerData{ServiceType: "real_time", IP: "66.112.148.225", Port: 60002, Token: "1a2b3c", Version: "6.2.0.25",
+				VerboseExchanges: "NASDAQ NYSE ", MaxSymbols: 1300, Flags: "NO_EOD"}}},
+		{statsLine[2:], SystemMessage{Type: SysStats, Stats: SystemStats{ServerIP: "66.112.148.225", ServerPort: 60002, MaxSymbols: 1300,
+			NumberOfSymbols: 2, ClientsConnected: 3, Reconnections: 1, AttemptedReconnections: 4,
+			StartTime: time.Date(year, time.March, 5, 9, 30, 0, 0, time.UTC), MarketTime: time.Date(year, time.March, 5, 10, 15, 0, 0, time.UTC),
+			Status: "Connected", IQFeedVersion: "6.2.0.25", LoginID: "123456", TotalKBsRecv: 1234.56, KBsPerSecRecv: 1.23,
+			AvgKBsPerSecRecv: 0.45, TotalKBsSent: 567.89, KBsPerSecSent: 0.98, AvgKBsPerSecSent: 0.12}}},
+		{"SOMETHING NEW,1,", SystemMessage{Type: SysUnknown}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			var got SystemMessage
+			err := got.UnMarshall([]byte(tt.line), time.UTC)
+			tt.want.Raw = tt.line
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnMarshall(%q) = %+v, %v, want %+v", tt.line, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSystemMessageErrors(t *testing.T) {
+	tests := []struct {
+		line    string
+		message string
+		field   string
+		value   string
+	}{
+		{"CUST,real_time,66.112.148.225,port,1a2b3c,6.2.0.25,0,NASDAQ NYSE ,,1300,NO_EOD,,,", "CustomerData", "Port", "port"},
+		{"CUST,real_time,66.112.148.225,60002,", "CustomerData", "Token", ""},
+		{"STATS,66.112.148.225,60002,1300,2,3,0,1,4,Mar 05 9:30AM,10:15AM,Connected,", "SystemStats", "MarketTime", "10:15AM"},
+		{"STATS,66.112.148.225,60002,1300,2,3,0,1,4,Mar 05 9:30AM,Mar 05 10:15AM,Connected,6.2.0.25,123456,", "SystemStats", "TotalKBsRecv", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			var s SystemMessage
+			err := s.UnMarshall([]byte(tt.line), time.UTC)
+			perr, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("UnMarshall(%q) = %v, want a *ParseError", tt.line, err)
+			}
+			if perr.Message != tt.message || perr.Field != tt.field || perr.Value != tt.value || perr.Raw != tt.line {
+				t.Errorf("UnMarshall(%q) = %+v", tt.line, perr)
+			}
+		})
+	}
+}
+
+// statsYear returns the year the Mar 5 times of statsLine are dated in.
+func statsYear(loc *time.Location) int {
+	now := time.Now().In(loc)
+	if time.Date(now.Year(), time.March, 5, 10, 15, 0, 0, loc).After(now.AddDate(0, 0, 1)) {
+		return now.Year() - 1
+	}
+	return now.Year()
+}
+
+func TestStatsTime(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	parse := func(v string) time.Time {
+		return newFieldParser("", v, []string{v}, loc).toTime("", v, layoutStatsTime)
+	}
+	tests := []struct {
+		name string
+		v    string
+		ref  time.Time
+		want time.Time
+	}{
+		{"same year", "Mar 5 10:15AM", time.Date(2024, time.March, 5, 10, 15, 30, 0, loc), time.Date(2024, time.March, 5, 10, 15, 0, 0, loc)},
+		{"previous year", "Dec 31 11:59PM", time.Date(2025, time.January, 1, 0, 1, 0, 0, loc), time.Date(2024, time.December, 31, 23, 59, 0, 0, loc)},
+		{"clock behind", "Mar 6 12:01AM", time.Date(2024, time.March, 5, 23, 59, 0, 0, loc), time.Date(2024, time.March, 6, 0, 1, 0, 0, loc)},
+		{"summer offset", "Jul 1 9:30AM", time.Date(2024, time.July, 1, 10, 0, 0, 0, loc), time.Date(2024, time.July, 1, 9, 30, 0, 0, loc)},
+		{"empty", "", time.Date(2024, time.July, 1, 10, 0, 0, 0, loc), time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getStatsTime(parse(tt.v), tt.ref); !got.Equal(tt.want) {
+				t.Errorf("getStatsTime(%q, %s) = %s, want %s", tt.v, tt.ref, got, tt.want)
+			}
+		})
+	}
+}
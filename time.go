@@ -7,8 +7,13 @@ type TimeMsg struct {
 	TimeStamp time.Time
 }
 
-// UnMarshall sends the data into the usable struct for consumption by the application.
-func (tm *TimeMsg) UnMarshall(d []byte, loc *time.Location) {
-	t, _ := time.ParseInLocation("20060102 15:04:05", string(d), loc)
-	tm.TimeStamp = t
+// UnMarshall sends the data into the usable struct for consumption by the application, a *ParseError is returned when
+// the timestamp is invalid.
+func (tm *TimeMsg) UnMarshall(d []byte, loc *time.Location) error {
+	p := newFieldParser("TimeMsg", string(d), []string{string(d)}, loc)
+	tm.TimeStamp = p.time(0, "TimeStamp", "20060102 15:04:05")
+	if tm.TimeStamp.IsZero() && p.err == nil {
+		p.fail("TimeStamp", "", errMissingField)
+	}
+	return p.error()
 }
@@ -0,0 +1,213 @@
+package iqfeed
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TradeConditionClass classifies how trades with a condition count towards the statistics of the day, conditions
+// without a class are regular trades.
+type TradeConditionClass uint8
+
+// The trade condition classes, a condition may have several.
+const (
+	CondOddLot         TradeConditionClass = 1 << iota // The trade is for less than a round lot.
+	CondOutOfSequence                                  // The trade was reported late or out of sequence.
+	CondExtendedHours                                  // The trade took place outside of the regular session (Form T).
+	CondExcludeLast                                    // The trade does not update the last price.
+	CondExcludeHighLow                                 // The trade does not update the high and low.
+	CondExcludeVolume                                  // The trade does not count towards the volume.
+)
+
+// Has reports whether every class in c is set.
+func (cl TradeConditionClass) Has(c TradeConditionClass) bool {
+	return cl&c == c
+}
+
+// TradeCondition is a decoded trade condition code as sent in UpdSummaryMsg.MostRecntTradeCond.
+type TradeCondition struct {
+	Code      int
+	ShortName string // ex: ODDLOT, the code in hex when it is not in the table
+	LongName  string
+	Class     TradeConditionClass
+}
+
+// UpdatesLast reports whether a trade with the condition may set the last price.
+func (c TradeCondition) UpdatesLast() bool {
+	return c.Class&(CondExcludeLast|CondOutOfSequence) == 0
+}
+
+// UpdatesHighLow reports whether a trade with the condition may set the high or the low.
+func (c TradeCondition) UpdatesHighLow() bool {
+	return !c.Class.Has(CondExcludeHighLow)
+}
+
+// UpdatesVolume reports whether a trade with the condition counts towards the volume.
+func (c TradeCondition) UpdatesVolume() bool {
+	return !c.Class.Has(CondExcludeVolume)
+}
+
+// tradeConditionClasses holds the class of the conditions by short name, so that the classes are kept when the table is
+// refreshed from the lookup port.
+var tradeConditionClasses = map[string]TradeConditionClass{
+	"CASHM":       CondExcludeLast | CondExcludeHighLow,
+	"AVGPRI":      CondExcludeLast | CondExcludeHighLow,
+	"CASH":        CondExcludeLast | CondExcludeHighLow,
+	"NEXTDAY":     CondExcludeLast | CondExcludeHighLow,
+	"BUNCHEDSOLD": CondOutOfSequence,
+	"ORDETAIL":    CondOutOfSequence,
+	"BSKTONCLOSE": CondExcludeLast | CondExcludeHighLow,
+	"SOLDLAST":    CondOutOfSequence,
+	"NEXTDAYCLR":  CondExcludeLast | CondExcludeHighLow,
+	"OPENED":      CondOutOfSequence,
+	"PRP":         CondExcludeLast | CondExcludeHighLow,
+	"SELLER":      CondExcludeLast | CondExcludeHighLow,
+	"FORMT":       CondExtendedHours | CondExcludeLast | CondExcludeHighLow,
+	"SOLDOSEQ":    CondOutOfSequence,
+	"ODDLOT":      CondOddLot | CondExcludeLast | CondExcludeHighLow,
+	// Cancels and corrections of an earlier trade, and the prices reported by the markets that are not trades.
+	"CANC":            CondExcludeLast | CondExcludeHighLow | CondExcludeVolume,
+	"CANCLAST":        CondExcludeLast | CondExcludeHighLow | CondExcludeVolume,
+	"CANCOPEN":        CondExcludeLast | CondExcludeHighLow | CondExcludeVolume,
+	"CANCONLY":        CondExcludeLast | CondExcludeHighLow | CondExcludeVolume,
+	"CANCSTPD":        CondExcludeLast | CondExcludeHighLow | CondExcludeVolume,
+	"NOMINAL":         CondExcludeLast | CondExcludeHighLow | CondExcludeVolume,
+	"MCOFFICIALCLOSE": CondExcludeLast | CondExcludeHighLow | CondExcludeVolume,
+	"MCOFFICIALOPEN":  CondExcludeLast | CondExcludeHighLow | CondExcludeVolume,
+	"SETTLEPRICE":     CondExcludeLast | CondExcludeHighLow | CondExcludeVolume,
+	"OFFICIALPRICE":   CondExcludeLast | CondExcludeHighLow | CondExcludeVolume,
+	"UNOFFICIALPRICE": CondExcludeLast | CondExcludeHighLow | CondExcludeVolume,
+	"MIDBIDASKPRICE":  CondExcludeLast | CondExcludeHighLow | CondExcludeVolume,
+}
+
+// builtinTradeConditions is the part of the trade conditions table (STC) used by equities, see:
+// http://www.iqfeed.net/dev/api/docs/TradeConditions.cfm.
+var builtinTradeConditions = []*CodeName{
+	{ID: 1, ShortName: "REGULAR", LongName: "Normal Trade"},
+	{ID: 2, ShortName: "ACQ", LongName: "Acquisition"},
+	{ID: 3, ShortName: "CASHM", LongName: "Cash Only Market"},
+	{ID: 4, ShortName: "BUNCHED", LongName: "Bunched Trade"},
+	{ID: 5, ShortName: "AVGPRI", LongName: "Average Price Trade"},
+	{ID: 6, ShortName: "CASH", LongName: "Cash Trade (same day clearing)"},
+	{ID: 7, ShortName: "NEXTDAY", LongName: "Next Day Market"},
+	{ID: 8, ShortName: "BURSTBSKT", LongName: "Burst Basket Execution"},
+	{ID: 9, ShortName: "BUNCHEDSOLD", LongName: "Bunched Sold Trade"},
+	{ID: 10, ShortName: "ORDETAIL", LongName: "Opening/Reopening Trade Detail"},
+	{ID: 11, ShortName: "INTERDAY", LongName: "Intraday Trade Detail"},
+	{ID: 12, ShortName: "BSKTONCLOSE", LongName: "Basket Index On Close"},
+	{ID: 13, ShortName: "RULE127", LongName: "Rule 127 Trade (NYSE only)"},
+	{ID: 14, ShortName: "RULE155", LongName: "Rule 155 Trade (NYSE MKT only)"},
+	{ID: 15, ShortName: "SOLDLAST", LongName: "Sold Last (Late Reporting)"},
+	{ID: 16, ShortName: "NEXTDAYCLR", LongName: "Next Day Clearing"},
+	{ID: 17, ShortName: "OPENED", LongName: "Opened (Late Report of Opening Trade)"},
+	{ID: 18, ShortName: "PRP", LongName: "Prior Reference Price"},
+	{ID: 19, ShortName: "SELLER", LongName: "Seller's Option"},
+	{ID: 20, ShortName: "SPLIT", LongName: "Split Trade"},
+	{ID: 22, ShortName: "FORMT", LongName: "Form-T Trade (extended hours)"},
+	{ID: 23, ShortName: "CUSTBSKTCROSS", LongName: "Custom Basket Cross"},
+	{ID: 24, ShortName: "SOLDOSEQ", LongName: "Sold Out of Sequence"},
+	{ID: 25, ShortName: "CANC", LongName: "Cancel"},
+	{ID: 26, ShortName: "CANCLAST", LongName: "Cancel Last"},
+	{ID: 27, ShortName: "CANCOPEN", LongName: "Cancel Open"},
+	{ID: 28, ShortName: "CANCONLY", LongName: "Cancel Only"},
+	{ID: 29, ShortName: "CANCSTPD", LongName: "Cancel Stopped"},
+	{ID: 32, ShortName: "NOMINAL", LongName: "Nominal Price"},
+	{ID: 36, ShortName: "MCOFFICIALCLOSE", LongName: "Market Center Official Close"},
+	{ID: 61, ShortName: "INTERMARKETSWEEP", LongName: "Intermarket Sweep Order"},
+	{ID: 135, ShortName: "ODDLOT", LongName: "Odd Lot Trade"},
+}
+
+// TradeConditionTable decodes the trade condition codes, it starts with the built-in table and can be refreshed with
+// the table served by the lookup port. It is safe for concurrent use.
+type TradeConditionTable struct {
+	mu    sync.RWMutex
+	codes map[int]TradeCondition
+}
+
+// DefaultTradeConditions is the table used by ParseTradeConditions and UpdSummaryMsg.TradeConditions, it is refreshed by
+// LookupClient.MarketTables.
+var DefaultTradeConditions = NewTradeConditionTable()
+
+// NewTradeConditionTable returns a table holding the built-in trade conditions.
+func NewTradeConditionTable() *TradeConditionTable {
+	t := &TradeConditionTable{}
+	t.Refresh(builtinTradeConditions)
+	return t
+}
+
+// Refresh replaces the conditions with rows of the trade conditions table, such as LookupClient.TradeConditions. The
+// classes are matched by short name so conditions the built-in table does not know are left unclassified.
+func (t *TradeConditionTable) Refresh(rows []*CodeName) {
+	codes := make(map[int]TradeCondition, len(rows))
+	for _, r := range rows {
+		codes[r.ID] = TradeCondition{
+			Code:      r.ID,
+			ShortName: r.ShortName,
+			LongName:  r.LongName,
+			Class:     tradeConditionClasses[strings.ToUpper(r.ShortName)],
+		}
+	}
+	t.mu.Lock()
+	t.codes = codes
+	t.mu.Unlock()
+}
+
+// RefreshFrom replaces the conditions with the trade conditions of the market tables, see LookupClient.MarketTables.
+func (t *TradeConditionTable) RefreshFrom(m *MarketTables) {
+	rows := make([]*CodeName, 0, len(m.TradeConditions))
+	for _, r := range m.TradeConditions {
+		rows = append(rows, r)
+	}
+	t.Refresh(rows)
+}
+
+// Lookup returns the condition with the code.
+func (t *TradeConditionTable) Lookup(code int) (TradeCondition, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	c, ok := t.codes[code]
+	return c, ok
+}
+
+// Parse decodes a trade condition string, it holds one or more conditions encoded as two hex digits each (ex: 013D is
+// conditions 1 and 61). Codes missing from the table are returned with their hex digits as ShortName.
+func (t *TradeConditionTable) Parse(cond string) []TradeCondition {
+	if len(cond) < 2 {
+		return nil
+	}
+	conds := make([]TradeCondition, 0, len(cond)/2)
+	for i := 0; i+1 < len(cond); i += 2 {
+		code, err := strconv.ParseUint(cond[i:i+2], 16, 8)
+		if err != nil {
+			conds = append(conds, TradeCondition{ShortName: cond[i : i+2]})
+			continue
+		}
+		c, ok := t.Lookup(int(code))
+		if !ok {
+			c = TradeCondition{Code: int(code), ShortName: cond[i : i+2]}
+		}
+		conds = append(conds, c)
+	}
+	return conds
+}
+
+// ParseTradeConditions decodes a trade condition string with DefaultTradeConditions.
+func ParseTradeConditions(cond string) []TradeCondition {
+	return DefaultTradeConditions.Parse(cond)
+}
+
+// TradeConditions decodes MostRecntTradeCond with DefaultTradeConditions.
+func (u *UpdSummaryMsg) TradeConditions() []TradeCondition {
+	return ParseTradeConditions(u.MostRecntTradeCond)
+}
+
+// TradeConditionClasses returns the classes of every condition of the most recent trade, ex: to leave odd lots and out
+// of sequence prints out of bars with Has(CondOddLot) or Has(CondOutOfSequence).
+func (u *UpdSummaryMsg) TradeConditionClasses() TradeConditionClass {
+	var cl TradeConditionClass
+	for _, c := range u.TradeConditions() {
+		cl |= c.Class
+	}
+	return cl
+}
@@ -1,6 +1,10 @@
 package iqfeed
 
-import "time"
+import (
+	"bytes"
+	"sync"
+	"time"
+)
 
 // UpdSummaryMsg is the main struct for both update and summary messages.
 type UpdSummaryMsg struct {
@@ -53,6 +57,8 @@ type UpdSummaryMsg struct {
 	MostRecentTradeMktCntr int       // Market Center of the most recent trade (including all non-last-qualified trades).
 	MostRecentTradeSize    int       // Size of the most recent trade (including all non-last-qualified trades).
 	MostRecentTradeTime    time.Time // Time (including microseconds) of the most recent trade (including all non-last-qualified trades).
+	MostRecentTradeAggr    int       // Side that initiated the most recent trade: 1 = buy, 2 = sell, 3 = neither. Protocol 6.1 and later.
+	MostRecentTradeDayCode int       // Day of the month of the trading session of the most recent trade. Protocol 6.1 and later.
 	NetAssetValue          float64   // Mutual Funds only. The market value of a mutual fund share equal to the net asset of a fund divided by the total number of shares outstanding. NOTE: this field is the same as the Bid field for Mutual Funds.
 	NetAssetValue2         float64   // Undocumented
 	NumTradesToday         int       // The number of trades for the current day.
@@ -83,153 +89,317 @@ type UpdSummaryMsg struct {
 	RegionalVol            int       // RegionalVol
 	Regions                string    // Undocumented
 	TradeTime              time.Time // TradeTime
+	Present                FieldSet  // The fields that were sent with a value, a blank field is left at its zero value.
+	// DecimalPrices keeps the exact prices of the price fields when it is set before UnMarshall, see Decimal.
+	DecimalPrices bool
+	// Extra holds the fields sent with a value that have no struct field by wire name so fields added by later protocols
+	// are not lost. It is empty when there are none, pooled messages keep the map of an earlier message for reuse so only
+	// test its length.
+	Extra map[string]string
+	// Contents is MsgContents decoded when the message is parsed, so trades and quotes can be told apart without
+	// scanning the string (ex: u.Contents.IsTrade()).
+	Contents ContentFlags
+	pooled   bool // The message was taken from updPool, see Release.
+	// decimals holds the exact prices by their position in updPrices, a fixed array so keeping them does not allocate.
+	decimals   [len(updPrices)]Price
+	decimalSet uint32 // The positions of decimals that were sent.
+}
+
+// updPrices are the update fields holding prices, they are kept exactly when DecimalPrices is set.
+var updPrices = [...]UpdField{FieldLast, FieldChange, FieldHigh, FieldLow, FieldBid, FieldAsk, FieldRange, FieldOpen,
+	FieldClose, FieldSpread, FieldStrike, FieldSettle, FieldNetAssetValue, FieldNetAssetValue2, FieldExtendedTradingLast,
+	FieldExtendedTradingChange, FieldExtendedTradingDifference, FieldBidChange, FieldAskChange, FieldChangeFromOpen,
+	FieldOpenRange1, FieldCloseRange1, FieldOpenRange2, FieldCloseRange2, FieldVWAP, FieldExtendedTrade,
+	FieldMostRecentTrade}
+
+// updPriceSlots holds the position of every update field in updPrices, -1 for the fields that are not prices.
+var updPriceSlots = func() (slots [numUpdFields]int8) {
+	for i := range slots {
+		slots[i] = -1
+	}
+	for i, f := range updPrices {
+		slots[f] = int8(i)
+	}
+	return slots
+}()
+
+// Decimal returns the exact price sent for the field, it is only available when DecimalPrices was set.
+func (u *UpdSummaryMsg) Decimal(field UpdField) (Price, bool) {
+	if field >= numUpdFields {
+		return Price{}, false
+	}
+	i := updPriceSlots[field]
+	if i < 0 || u.decimalSet&(1<<i) == 0 {
+		return Price{}, false
+	}
+	return u.decimals[i], true
+}
+
+// AttachDate turns the time-only fields into full instants. Trade times use the date sent with them in the message
+// (ex: Last Date for Last Time), the other times and trades sent without a date use the date of the feed time ref, which
+// is the latest T message for messages received from IQC.
+func (u *UpdSummaryMsg) AttachDate(ref time.Time) {
+	lastDate := u.LastDate
+	if lastDate.IsZero() {
+		// The 4.x fieldset sends the date of the last trade as Last Trade Date.
+		lastDate = u.LastTrdDate
+	}
+	u.LastTime = attachMsgDate(lastDate, ref, u.LastTime)
+	u.MostRecentTradeTime = attachMsgDate(u.MostRecntTradeDate, ref, u.MostRecentTradeTime)
+	u.ExtendedTrdTime = attachMsgDate(u.ExtendedTrdDate, ref, u.ExtendedTrdTime)
+	u.BidTime = attachFeedDate(ref, u.BidTime)
+	u.AskTime = attachFeedDate(ref, u.AskTime)
+	u.TradeTime = attachFeedDate(ref, u.TradeTime)
+}
+
+// Has reports whether the field was sent with a value, so a sparse update can be told apart from a zero price or size.
+func (u *UpdSummaryMsg) Has(field UpdField) bool {
+	return u.Present.Has(field)
+}
+
+// updTarget returns the struct field of u an update field is stored in, a *string, *int, *float64 or *time.Time.
+type updTarget func(u *UpdSummaryMsg) any
+
+// updTargets maps the wire names to the struct fields and, for times and dates, the layout they are sent in. The 4.x
+// names come first, Last Trade Time and Market Center are the 4.x names of Last Time and Last Market Center.
+var updTargets = map[string]struct {
+	layout string
+	target updTarget
+}{
+	"Symbol":                      {"", func(u *UpdSummaryMsg) any { return &u.Symbol }},
+	"Exchange ID":                 {"", func(u *UpdSummaryMsg) any { return &u.ExchangeID }},
+	"Last":                        {"", func(u *UpdSummaryMsg) any { return &u.Last }},
+	"Change":                      {"", func(u *UpdSummaryMsg) any { return &u.Change }},
+	"Percent Change":              {"", func(u *UpdSummaryMsg) any { return &u.PcntChange }},
+	"Total Volume":                {"", func(u *UpdSummaryMsg) any { return &u.TotalVol }},
+	"Incremental Volume":          {"", func(u *UpdSummaryMsg) any { return &u.IncrVolume }},
+	"High":                        {"", func(u *UpdSummaryMsg) any { return &u.High }},
+	"Low":                         {"", func(u *UpdSummaryMsg) any { return &u.Low }},
+	"Bid":                         {"", func(u *UpdSummaryMsg) any { return &u.Bid }},
+	"Ask":                         {"", func(u *UpdSummaryMsg) any { return &u.Ask }},
+	"Bid Size":                    {"", func(u *UpdSummaryMsg) any { return &u.BidSize }},
+	"Ask Size":                    {"", func(u *UpdSummaryMsg) any { return &u.AskSize }},
+	"Tick":                        {"", func(u *UpdSummaryMsg) any { return &u.Tick }},
+	"Bid Tick":                    {"", func(u *UpdSummaryMsg) any { return &u.BidTick }},
+	"Range":                       {"", func(u *UpdSummaryMsg) any { return &u.Range }},
+	"Last Trade Time":             {layoutHMS, func(u *UpdSummaryMsg) any { return &u.LastTime }},
+	"Open Interest":               {"", func(u *UpdSummaryMsg) any { return &u.OpenInterest }},
+	"Open":                        {"", func(u *UpdSummaryMsg) any { return &u.Open }},
+	"Close":                       {"", func(u *UpdSummaryMsg) any { return &u.Close }},
+	"Spread":                      {"", func(u *UpdSummaryMsg) any { return &u.Spread }},
+	"Strike":                      {"", func(u *UpdSummaryMsg) any { return &u.Strike }},
+	"Settle":                      {"", func(u *UpdSummaryMsg) any { return &u.Settle }},
+	"Delay":                       {"", func(u *UpdSummaryMsg) any { return &u.Delay }},
+	"Market Center":               {"", func(u *UpdSummaryMsg) any { return &u.LastMktCntr }},
+	"Restricted Code":             {"", func(u *UpdSummaryMsg) any { return &u.RestrictedCode }},
+	"Net Asset Value":             {"", func(u *UpdSummaryMsg) any { return &u.NetAssetValue }},
+	"Average Maturity":            {"", func(u *UpdSummaryMsg) any { return &u.AvgMaturity }},
+	"7 Day Yield":                 {"", func(u *UpdSummaryMsg) any { return &u.SevenDayYield }},
+	"Last Trade Date":             {layoutMMDDCCYY, func(u *UpdSummaryMsg) any { return &u.LastTrdDate }},
+	"(Reserved)":                  {"", func(u *UpdSummaryMsg) any { return &u.Reserved1 }},
+	"Extended Trading Last":       {"", func(u *UpdSummaryMsg) any { return &u.ExtendedTrdLast }},
+	"Expiration Date":             {layoutMMDDCCYY, func(u *UpdSummaryMsg) any { return &u.ExpirationDate }},
+	"Regional Volume":             {"", func(u *UpdSummaryMsg) any { return &u.RegionalVol }},
+	"Net Asset Value 2":           {"", func(u *UpdSummaryMsg) any { return &u.NetAssetValue2 }},
+	"Extended Trading Change":     {"", func(u *UpdSummaryMsg) any { return &u.ExtendedTrdChange }},
+	"Extended Trading Difference": {"", func(u *UpdSummaryMsg) any { return &u.ExtendedTrdDiff }},
+	"Price-Earnings Ratio":        {"", func(u *UpdSummaryMsg) any { return &u.PERatio }},
+	"Percent Off Average Volume":  {"", func(u *UpdSummaryMsg) any { return &u.PcntOffAvgVol }},
+	"Bid Change":                  {"", func(u *UpdSummaryMsg) any { return &u.BidChange }},
+	"Ask Change":                  {"", func(u *UpdSummaryMsg) any { return &u.AskChange }},
+	"Change From Open":            {"", func(u *UpdSummaryMsg) any { return &u.ChangeFrmOpen }},
+	"Market Open":                 {"", func(u *UpdSummaryMsg) any { return &u.MktOpen }},
+	"Volatility":                  {"", func(u *UpdSummaryMsg) any { return &u.Volatility }},
+	"Market Capitalization":       {"", func(u *UpdSummaryMsg) any { return &u.MktCapitilization }},
+	"Fraction Display Code":       {"", func(u *UpdSummaryMsg) any { return &u.FractionDispCode }},
+	"Decimal Precision":           {"", func(u *UpdSummaryMsg) any { return &u.DecPrecision }},
+	"Days to Expiration":          {"", func(u *UpdSummaryMsg) any { return &u.DaysToExpir }},
+	"Previous Day Volume":         {"", func(u *UpdSummaryMsg) any { return &u.PrevDayVol }},
+	"Regions":                     {"", func(u *UpdSummaryMsg) any { return &u.Regions }},
+	"Open Range 1":                {"", func(u *UpdSummaryMsg) any { return &u.OpenRange1 }},
+	"Close Range 1":               {"", func(u *UpdSummaryMsg) any { return &u.CloseRng1 }},
+	"Open Range 2":                {"", func(u *UpdSummaryMsg) any { return &u.OpenRange2 }},
+	"Close Range 2":               {"", func(u *UpdSummaryMsg) any { return &u.CloseRng2 }},
+	"Number of Trades Today":      {"", func(u *UpdSummaryMsg) any { return &u.NumTradesToday }},
+	"Bid Time":                    {layoutHMS, func(u *UpdSummaryMsg) any { return &u.BidTime }},
+	"Ask Time":                    {layoutHMS, func(u *UpdSummaryMsg) any { return &u.AskTime }},
+	"VWAP":                        {"", func(u *UpdSummaryMsg) any { return &u.VWAP }},
+	"TickID":                      {"", func(u *UpdSummaryMsg) any { return &u.TickID }},
+	"Financial Status Indicator":  {"", func(u *UpdSummaryMsg) any { return &u.FinancialStatusInd }},
+	"Settlement Date":             {layoutMMDDCCYY, func(u *UpdSummaryMsg) any { return &u.SettleDate }},
+	"Trade Market Center":         {"", func(u *UpdSummaryMsg) any { return &u.MostRecentTradeMktCntr }},
+	"Bid Market Center":           {"", func(u *UpdSummaryMsg) any { return &u.BidMktCenter }},
+	"Ask Market Center":           {"", func(u *UpdSummaryMsg) any { return &u.AskMktCenter }},
+	"Trade Time":                  {layoutHMS, func(u *UpdSummaryMsg) any { return &u.TradeTime }},
+	"Available Regions":           {"", func(u *UpdSummaryMsg) any { return &u.AvailRegions }},
+	"Type":                        {"", func(u *UpdSummaryMsg) any { return &u.Type }},
+
+	// The trade fields of the 5.x and 6.x protocols.
+	"Extended Trade":                  {"", func(u *UpdSummaryMsg) any { return &u.ExtendedTrdLast }},
+	"Extended Trade Date":             {layoutMMDDCCYY, func(u *UpdSummaryMsg) any { return &u.ExtendedTrdDate }},
+	"Extended Trade Market Center":    {"", func(u *UpdSummaryMsg) any { return &u.ExtendedTrdMktCntr }},
+	"Extended Trade Size":             {"", func(u *UpdSummaryMsg) any { return &u.ExtendedTrdSize }},
+	"Extended Trade Time":             {layoutHMS, func(u *UpdSummaryMsg) any { return &u.ExtendedTrdTime }},
+	"Last Date":                       {layoutMMDDCCYY, func(u *UpdSummaryMsg) any { return &u.LastDate }},
+	"Last Market Center":              {"", func(u *UpdSummaryMsg) any { return &u.LastMktCntr }},
+	"Last Size":                       {"", func(u *UpdSummaryMsg) any { return &u.LastSize }},
+	"Last Time":                       {layoutHMS, func(u *UpdSummaryMsg) any { return &u.LastTime }},
+	"Message Contents":                {"", func(u *UpdSummaryMsg) any { return &u.MsgContents }},
+	"Most Recent Trade":               {"", func(u *UpdSummaryMsg) any { return &u.MostRecentTrade }},
+	"Most Recent Trade Conditions":    {"", func(u *UpdSummaryMsg) any { return &u.MostRecntTradeCond }},
+	"Most Recent Trade Date":          {layoutMMDDCCYY, func(u *UpdSummaryMsg) any { return &u.MostRecntTradeDate }},
+	"Most Recent Trade Market Center": {"", func(u *UpdSummaryMsg) any { return &u.MostRecentTradeMktCntr }},
+	"Most Recent Trade Size":          {"", func(u *UpdSummaryMsg) any { return &u.MostRecentTradeSize }},
+	"Most Recent Trade Time":          {layoutHMS, func(u *UpdSummaryMsg) any { return &u.MostRecentTradeTime }},
+	"Most Recent Trade Aggressor":     {"", func(u *UpdSummaryMsg) any { return &u.MostRecentTradeAggr }},
+	"Most Recent Trade Day Code":      {"", func(u *UpdSummaryMsg) any { return &u.MostRecentTradeDayCode }},
+}
+
+// updColumn is the parser for one position of the update fieldset, the columns are built once when the field names are
+// received so the lines can be parsed without looking up each field by name.
+type updColumn struct {
+	name   string // The wire name, reported in parse errors.
+	field  UpdField
+	known  bool // Field is set, the name is a known UpdField.
+	price  int8 // The position of the field in updPrices, -1 for the fields that are not prices.
+	layout string
+	target updTarget // Nil for fields the message has no struct field for, they are kept in Extra.
+}
+
+// newUpdColumn returns the column for the field name, names sent by protocols before 6.0 must be mapped first.
+func newUpdColumn(name string) updColumn {
+	col := updColumn{name: name}
+	col.field, col.known = updFieldsByName[name]
+	col.price = -1
+	if col.known {
+		col.price = updPriceSlots[col.field]
+	}
+	if t, ok := updTargets[name]; ok {
+		col.layout, col.target = t.layout, t.target
+	}
+	return col
+}
+
+// newUpdColumns returns the columns for the positional field names (ex: IQC.DynFields).
+func newUpdColumns(fields map[int]string) []updColumn {
+	n := 0
+	for i := range fields {
+		if i >= n {
+			n = i + 1
+		}
+	}
+	columns := make([]updColumn, n)
+	for i := range columns {
+		columns[i] = newUpdColumn(fields[i])
+	}
+	return columns
+}
+
+// UnMarshall sends the data into the usable struct for consumption by the application, a *ParseError is returned when
+// a field is invalid. IQC parses the lines with the columns built for its fieldset instead, without splitting them.
+func (u *UpdSummaryMsg) UnMarshall(items []string, fields map[int]string, loc *time.Location) error {
+	p := &fieldParser{msg: "UpdSummaryMsg", items: items, loc: loc}
+	columns := newUpdColumns(fields)
+	for i, v := range items {
+		if i < len(columns) {
+			u.set(p, &columns[i], []byte(v))
+		}
+	}
+	u.finish()
+	return p.error()
+}
+
+// unMarshallLine parses the line (ex: AAPL,95.0200,100,...) field by field in place, strings are taken from strs when it
+// is non-nil so that repeated values such as the symbol are not allocated for every message.
+func (u *UpdSummaryMsg) unMarshallLine(d []byte, columns []updColumn, loc *time.Location, strs *stringCache) error {
+	p := fieldParser{msg: "UpdSummaryMsg", line: d, loc: loc, strs: strs}
+	for i := 0; ; i++ {
+		end := bytes.IndexByte(d, ',')
+		if end < 0 {
+			end = len(d)
+		}
+		if i < len(columns) {
+			u.set(&p, &columns[i], d[:end])
+		}
+		if end == len(d) {
+			break
+		}
+		d = d[end+1:]
+	}
+	u.finish()
+	return p.error()
+}
 
+// Finish decodes the fields that depend on others once every field is set.
+func (u *UpdSummaryMsg) finish() {
+	u.Contents = ParseContentFlags(u.MsgContents)
+	if u.decimalSet == 0 || u.DecPrecision == "" {
+		return
+	}
+	if prec := GetIntFromStr(u.DecPrecision); prec > 0 {
+		// Prices are padded to the precision of the symbol so they compare at a consistent scale.
+		for i, d := range u.decimals {
+			if u.decimalSet&(1<<i) == 0 {
+				continue
+			}
+			if r, ok := d.Rescale(uint8(prec)); ok && d.Scale < r.Scale {
+				u.decimals[i] = r
+			}
+		}
+	}
 }
 
-// UnMarshall sends the data into the usable struct for consumption by the application.
-func (u *UpdSummaryMsg) UnMarshall(items []string, fields map[int]string, loc *time.Location) {
-	//DynFields: map[4:Most Recent Trade Market Center 7:Bid Size 11:High 1:Most Recent Trade 8:Ask 9:Ask Size 12:Low 10:Open 15:Most Recent Trade Conditions 13:Close 14:Message Contents 0:Symbol 2:Most Recent Trade Size 3:Most Recent Trade TimeMS 5:Total Volume 6:Bid]
-	//Unmarshall: AAPL,95.0200,100,09:35:57.022,26,1325032,95.0200,100,95.0400,400,95.0000,95.3800,94.8600,94.4800,ba,01,
-	//fmt.Printf("Dyn: %#v\nItems: %#v\n", fields, items)
-	//time.Sleep(50 * time.Millisecond)
-	//fmt.Printf("Unmarshall: %#v\n", items)
-	for k, v := range items {
-
-		switch fields[k] {
-		case "Symbol":
-			u.Symbol = v
-		case "Exchange ID":
-			u.ExchangeID = v
-		case "Last":
-			u.Last = GetFloatFromStr(v)
-		case "Change":
-			u.Change = GetFloatFromStr(v)
-		case "Percent Change":
-			u.PcntChange = GetFloatFromStr(v)
-		case "Total Volume":
-			u.TotalVol = GetIntFromStr(v)
-		case "Incremental Volume":
-			u.IncrVolume = GetIntFromStr(v)
-		case "High":
-			u.High = GetFloatFromStr(v)
-		case "Low":
-			u.Low = GetFloatFromStr(v)
-		case "Bid":
-			u.Bid = GetFloatFromStr(v)
-		case "Ask":
-			u.Ask = GetFloatFromStr(v)
-		case "Bid Size":
-			u.BidSize = GetIntFromStr(v)
-		case "Ask Size":
-			u.AskSize = GetIntFromStr(v)
-		case "Tick":
-			u.Tick = GetIntFromStr(v)
-		case "Bid Tick":
-			u.BidTick = v
-		case "Range":
-			u.Range = GetFloatFromStr(v)
-		case "Last Trade Time":
-			u.LastTrdDate = GetTimeInHMS(v, loc)
-		case "Open Interest":
-			u.OpenInterest = GetIntFromStr(v)
-		case "Open":
-			u.Open = GetFloatFromStr(v)
-		case "Close":
-			u.Close = GetFloatFromStr(v)
-		case "Spread":
-			u.Spread = GetFloatFromStr(v)
-		case "Strike":
-			u.Strike = GetFloatFromStr(v)
-		case "Settle":
-			u.Settle = GetFloatFromStr(v)
-		case "Delay":
-			u.Delay = GetIntFromStr(v)
-		case "Market Center":
-			u.AskMktCenter = GetIntFromStr(v)
-		case "Restricted Code":
-			u.RestrictedCode = v
-		case "Net Asset Value":
-			u.NetAssetValue = GetFloatFromStr(v)
-		case "Average Maturity":
-			u.AvgMaturity = GetFloatFromStr(v)
-		case "7 Day Yield":
-			u.SevenDayYield = GetFloatFromStr(v)
-		case "Last Trade Date":
-			u.LastTrdDate = GetDateMMDDCCYY(v, loc)
-		case "(Reserved)":
-			u.Reserved1 = v
-		case "Extended Trading Last":
-			u.ExtendedTrdLast = GetFloatFromStr(v)
-		case "Expiration Date":
-			u.ExpirationDate = GetDateMMDDCCYY(v, loc)
-		case "Regional Volume":
-			u.RegionalVol = GetIntFromStr(v)
-		case "Net Asset Value 2":
-			u.NetAssetValue2 = GetFloatFromStr(v)
-		case "Extended Trading Change":
-			u.ExtendedTrdChange = GetFloatFromStr(v)
-		case "Extended Trading Difference":
-			u.ExtendedTrdDiff = GetFloatFromStr(v)
-		case "Price-Earnings Ratio":
-			u.PERatio = GetFloatFromStr(v)
-		case "Percent Off Average Volume":
-			u.PcntOffAvgVol = GetFloatFromStr(v)
-		case "Bid Change":
-			u.BidChange = GetFloatFromStr(v)
-		case "Ask Change":
-			u.AskChange = GetFloatFromStr(v)
-		case "Change From Open":
-			u.ChangeFrmOpen = GetFloatFromStr(v)
-		case "Market Open":
-			u.MktOpen = GetIntFromStr(v)
-		case "Volatility":
-			u.Volatility = GetFloatFromStr(v)
-		case "Market Capitalization":
-			u.MktCapitilization = GetFloatFromStr(v)
-		case "Fraction Display Code":
-			u.FractionDispCode = v
-		case "Decimal Precision":
-			u.DecPrecision = v
-		case "Days to Expiration":
-			u.DaysToExpir = v
-		case "Previous Day Volume":
-			u.PrevDayVol = GetIntFromStr(v)
-		case "Regions":
-			u.Regions = v
-		case "Open Range 1":
-			u.OpenRange1 = GetFloatFromStr(v)
-		case "Close Range 1":
-			u.CloseRng1 = GetFloatFromStr(v)
-		case "Open Range 2":
-			u.OpenRange2 = GetFloatFromStr(v)
-		case "Close Range 2":
-			u.CloseRng2 = GetFloatFromStr(v)
-		case "Number of Trades Today":
-			u.NumTradesToday = GetIntFromStr(v)
-		case "Bid Time":
-			u.BidTime = GetTimeInHMS(v, loc)
-		case "Ask Time":
-			u.AskTime = GetTimeInHMS(v, loc)
-		case "VWAP":
-			u.VWAP = GetFloatFromStr(v)
-		case "TickID":
-			u.TickID = GetIntFromStr(v)
-		case "Financial Status Indicator":
-			u.FinancialStatusInd = v
-		case "Settlement Date":
-			u.SettleDate = GetDateMMDDCCYY(v, loc)
-		case "Trade Market Center":
-			u.MostRecentTradeMktCntr = GetIntFromStr(v)
-		case "Bid Market Center":
-			u.BidMktCenter = GetIntFromStr(v)
-		case "Ask Market Center":
-			u.AskMktCenter = GetIntFromStr(v)
-		case "Trade Time":
-			u.TradeTime = GetTimeInHMS(v, loc)
-		case "Available Regions":
-			u.AvailRegions = v
-		case "Type":
-			u.Type = v
+// Set stores the value of a single column.
+func (u *UpdSummaryMsg) set(p *fieldParser, col *updColumn, v []byte) {
+	if col.known && len(v) > 0 {
+		u.Present.Add(col.field)
+		if u.DecimalPrices && col.price >= 0 {
+			u.decimals[col.price] = p.bytesPrice(col.name, v)
+			u.decimalSet |= 1 << col.price
 		}
 	}
+	if col.target == nil {
+		if col.name != "" && len(v) > 0 {
+			if u.Extra == nil {
+				u.Extra = make(map[string]string)
+			}
+			u.Extra[col.name] = p.bytesStr(v)
+		}
+		return
+	}
+	switch f := col.target(u).(type) {
+	case *string:
+		*f = p.bytesStr(v)
+	case *int:
+		*f = p.bytesInt(col.name, v)
+	case *float64:
+		*f = p.bytesFloat(col.name, v)
+	case *time.Time:
+		*f = p.bytesTime(col.name, v, col.layout)
+	}
+}
+
+// updPool holds the released messages of the clients with IQC.PoolUpdates set.
+var updPool = sync.Pool{New: func() any { return new(UpdSummaryMsg) }}
+
+// getUpdSummaryMsg returns an empty message from the pool, it is returned with Release.
+func getUpdSummaryMsg() *UpdSummaryMsg {
+	u := updPool.Get().(*UpdSummaryMsg)
+	u.pooled = true
+	return u
+}
+
+// Release returns a message received from a client with IQC.PoolUpdates set to the pool, the message and its Extra must
+// not be used afterwards. It does nothing for messages that were not taken from the pool.
+func (u *UpdSummaryMsg) Release() {
+	if !u.pooled {
+		return
+	}
+	extra := u.Extra
+	*u = UpdSummaryMsg{}
+	if extra != nil {
+		clear(extra)
+		u.Extra = extra
+	}
+	updPool.Put(u)
 }
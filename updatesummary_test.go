@@ -0,0 +1,374 @@
+package iqfeed
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nitecon/iqfeed/iqfeedtest"
+)
+
+// benchUpdate is an update for the default 5.x fieldset.
+const benchUpdate = "AAPL,95.0200,100,09:35:57.022,26,1325032,95.0200,100,95.0400,400,95.0000,95.3800,94.8600,94.4800,ba,01,"
+
+func benchFields() map[int]string {
+	fields := make(map[int]string, len(iqfeedtest.DefaultUpdateFields))
+	for i, name := range iqfeedtest.DefaultUpdateFields {
+		fields[i] = updateFieldName(name, ProtocolVersion{Major: 5, Minor: 1})
+	}
+	return fields
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestUpdateGolden parses the summary in testdata/update-<protocol>.txt, sent with the fieldset on its first line, and
+// compares every struct field with the golden file.
+func TestUpdateGolden(t *testing.T) {
+	files, _ := filepath.Glob("testdata/update-*.txt")
+	if len(files) == 0 {
+		t.Fatal("no golden inputs in testdata")
+	}
+	for _, file := range files {
+		protocol := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "update-"), ".txt")
+		t.Run(protocol, func(t *testing.T) {
+			v, err := ParseProtocolVersion(protocol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			sc := bufio.NewScanner(f)
+			sc.Buffer(nil, 1<<16)
+			var lines []string
+			for sc.Scan() {
+				lines = append(lines, sc.Text())
+			}
+			if len(lines) != 2 {
+				t.Fatalf("%s: want the field names and a summary line, got %d lines", file, len(lines))
+			}
+			s := &SystemMessage{}
+			s.UnMarshall([]byte(strings.TrimPrefix(lines[0], "S,")), time.UTC)
+			fields := make(map[int]string, len(s.Fields))
+			for i, name := range s.Fields {
+				fields[i] = updateFieldName(name, v)
+				if _, ok := updTargets[fields[i]]; !ok {
+					t.Errorf("%q has no struct field", name)
+				}
+			}
+			u := &UpdSummaryMsg{}
+			if err := u.UnMarshall(strings.Split(strings.TrimPrefix(lines[1], "P,"), ","), fields, time.UTC); err != nil {
+				t.Fatal(err)
+			}
+			got := formatUpdate(u)
+			golden := strings.TrimSuffix(file, ".txt") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("%s does not match, run go test -update to review the changes:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// formatUpdate lists the exported fields of u in declaration order, one per line.
+func formatUpdate(u *UpdSummaryMsg) string {
+	var b strings.Builder
+	rv := reflect.ValueOf(u).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		sf := rv.Type().Field(i)
+		switch {
+		case !sf.IsExported() || sf.Name == "DecimalPrices":
+		case sf.Name == "Present":
+			fmt.Fprintf(&b, "Present: %v\n", u.Present.Fields())
+		case sf.Name == "Extra":
+			names := make([]string, 0, len(u.Extra))
+			for name := range u.Extra {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Fprintf(&b, "Extra[%s]: %s\n", name, u.Extra[name])
+			}
+		default:
+			v := rv.Field(i).Interface()
+			if t, ok := v.(time.Time); ok {
+				v = t.Format("2006-01-02 15:04:05.999999")
+			}
+			fmt.Fprintf(&b, "%s: %v\n", sf.Name, v)
+		}
+	}
+	return b.String()
+}
+
+func TestUnMarshallLine(t *testing.T) {
+	fields := benchFields()
+	want := &UpdSummaryMsg{}
+	if err := want.UnMarshall(strings.Split(benchUpdate, ","), fields, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	var strs stringCache
+	for i := 0; i < 2; i++ {
+		u := getUpdSummaryMsg()
+		if err := u.unMarshallLine([]byte(benchUpdate), newUpdColumns(fields), time.UTC, &strs); err != nil {
+			t.Fatal(err)
+		}
+		if u.Symbol != "AAPL" || u.MostRecentTradeTime != want.MostRecentTradeTime || u.Present != want.Present {
+			t.Errorf("unMarshallLine = %+v, want %+v", u, want)
+		}
+		u.Release()
+	}
+
+	for _, v := range []string{"09:35:57", "09:35:57.022", "23:59:59.123456789", "9:35:57", "24:00:00", "09:35:57.", "09:35"} {
+		want, werr := time.ParseInLocation(layoutHMS, v, time.UTC)
+		got, ok := parseHMS([]byte(v), time.UTC)
+		if ok && (werr != nil || !got.Equal(want)) {
+			t.Errorf("parseHMS(%s) = %s, want %s, %v", v, got, want, werr)
+		}
+	}
+	for _, v := range []string{"03/01/2024", "02/29/2024", "02/30/2024", "13/01/2024", "3/1/2024"} {
+		want, werr := time.ParseInLocation(layoutMMDDCCYY, v, time.UTC)
+		got, ok := parseMMDDCCYY([]byte(v), time.UTC)
+		if ok != (werr == nil) || ok && !got.Equal(want) {
+			t.Errorf("parseMMDDCCYY(%s) = %s, %t, want %s, %v", v, got, ok, want, werr)
+		}
+	}
+}
+
+func TestUnMarshallItems(t *testing.T) {
+	// The items are parsed as given, a value holding a comma does not shift the columns after it.
+	fields := map[int]string{0: "Symbol", 1: "Some New Text", 2: "Total Volume"}
+	u := &UpdSummaryMsg{}
+	if err := u.UnMarshall([]string{"AAPL", "a,b", "100"}, fields, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	if u.TotalVol != 100 || u.Extra["Some New Text"] != "a,b" {
+		t.Errorf("UnMarshall = %d, %q", u.TotalVol, u.Extra)
+	}
+	err := u.UnMarshall([]string{"AAPL", "", "lots"}, fields, time.UTC)
+	if perr, ok := err.(*ParseError); !ok || perr.Field != "Total Volume" || perr.Raw != "AAPL,,lots" {
+		t.Errorf("UnMarshall of an invalid volume = %v", err)
+	}
+}
+
+// unMarshallSwitch is a copy of UnMarshall as it was before the column table, looking up every field by name in a string
+// switch. It is kept as the baseline of the benchmarks, only the decimal prices were left out.
+func unMarshallSwitch(u *UpdSummaryMsg, items []string, fields map[int]string, loc *time.Location) error {
+	p := newFieldParser("UpdSummaryMsg", strings.Join(items, ","), items, loc)
+	for k, v := range items {
+		name := fields[k]
+		if f, ok := updFieldsByName[name]; ok && v != "" {
+			u.Present.Add(f)
+		}
+		switch name {
+		case "Symbol":
+			u.Symbol = v
+		case "Exchange ID":
+			u.ExchangeID = v
+		case "Last":
+			u.Last = p.toFloat(name, v)
+		case "Change":
+			u.Change = p.toFloat(name, v)
+		case "Percent Change":
+			u.PcntChange = p.toFloat(name, v)
+		case "Total Volume":
+			u.TotalVol = p.toInt(name, v)
+		case "Incremental Volume":
+			u.IncrVolume = p.toInt(name, v)
+		case "High":
+			u.High = p.toFloat(name, v)
+		case "Low":
+			u.Low = p.toFloat(name, v)
+		case "Bid":
+			u.Bid = p.toFloat(name, v)
+		case "Ask":
+			u.Ask = p.toFloat(name, v)
+		case "Bid Size":
+			u.BidSize = p.toInt(name, v)
+		case "Ask Size":
+			u.AskSize = p.toInt(name, v)
+		case "Tick":
+			u.Tick = p.toInt(name, v)
+		case "Bid Tick":
+			u.BidTick = v
+		case "Range":
+			u.Range = p.toFloat(name, v)
+		case "Last Trade Time":
+			u.LastTrdDate = p.toTime(name, v, layoutHMS)
+		case "Open Interest":
+			u.OpenInterest = p.toInt(name, v)
+		case "Open":
+			u.Open = p.toFloat(name, v)
+		case "Close":
+			u.Close = p.toFloat(name, v)
+		case "Spread":
+			u.Spread = p.toFloat(name, v)
+		case "Strike":
+			u.Strike = p.toFloat(name, v)
+		case "Settle":
+			u.Settle = p.toFloat(name, v)
+		case "Delay":
+			u.Delay = p.toInt(name, v)
+		case "Market Center":
+			u.AskMktCenter = p.toInt(name, v)
+		case "Restricted Code":
+			u.RestrictedCode = v
+		case "Net Asset Value":
+			u.NetAssetValue = p.toFloat(name, v)
+		case "Average Maturity":
+			u.AvgMaturity = p.toFloat(name, v)
+		case "7 Day Yield":
+			u.SevenDayYield = p.toFloat(name, v)
+		case "Last Trade Date":
+			u.LastTrdDate = p.toTime(name, v, layoutMMDDCCYY)
+		case "(Reserved)":
+			u.Reserved1 = v
+		case "Extended Trading Last":
+			u.ExtendedTrdLast = p.toFloat(name, v)
+		case "Expiration Date":
+			u.ExpirationDate = p.toTime(name, v, layoutMMDDCCYY)
+		case "Regional Volume":
+			u.RegionalVol = p.toInt(name, v)
+		case "Net Asset Value 2":
+			u.NetAssetValue2 = p.toFloat(name, v)
+		case "Extended Trading Change":
+			u.ExtendedTrdChange = p.toFloat(name, v)
+		case "Extended Trading Difference":
+			u.ExtendedTrdDiff = p.toFloat(name, v)
+		case "Price-Earnings Ratio":
+			u.PERatio = p.toFloat(name, v)
+		case "Percent Off Average Volume":
+			u.PcntOffAvgVol = p.toFloat(name, v)
+		case "Bid Change":
+			u.BidChange = p.toFloat(name, v)
+		case "Ask Change":
+			u.AskChange = p.toFloat(name, v)
+		case "Change From Open":
+			u.ChangeFrmOpen = p.toFloat(name, v)
+		case "Market Open":
+			u.MktOpen = p.toInt(name, v)
+		case "Volatility":
+			u.Volatility = p.toFloat(name, v)
+		case "Market Capitalization":
+			u.MktCapitilization = p.toFloat(name, v)
+		case "Fraction Display Code":
+			u.FractionDispCode = v
+		case "Decimal Precision":
+			u.DecPrecision = v
+		case "Days to Expiration":
+			u.DaysToExpir = v
+		case "Previous Day Volume":
+			u.PrevDayVol = p.toInt(name, v)
+		case "Regions":
+			u.Regions = v
+		case "Open Range 1":
+			u.OpenRange1 = p.toFloat(name, v)
+		case "Close Range 1":
+			u.CloseRng1 = p.toFloat(name, v)
+		case "Open Range 2":
+			u.OpenRange2 = p.toFloat(name, v)
+		case "Close Range 2":
+			u.CloseRng2 = p.toFloat(name, v)
+		case "Number of Trades Today":
+			u.NumTradesToday = p.toInt(name, v)
+		case "Bid Time":
+			u.BidTime = p.toTime(name, v, layoutHMS)
+		case "Ask Time":
+			u.AskTime = p.toTime(name, v, layoutHMS)
+		case "VWAP":
+			u.VWAP = p.toFloat(name, v)
+		case "TickID":
+			u.TickID = p.toInt(name, v)
+		case "Financial Status Indicator":
+			u.FinancialStatusInd = v
+		case "Settlement Date":
+			u.SettleDate = p.toTime(name, v, layoutMMDDCCYY)
+		case "Trade Market Center":
+			u.MostRecentTradeMktCntr = p.toInt(name, v)
+		case "Bid Market Center":
+			u.BidMktCenter = p.toInt(name, v)
+		case "Ask Market Center":
+			u.AskMktCenter = p.toInt(name, v)
+		case "Trade Time":
+			u.TradeTime = p.toTime(name, v, layoutHMS)
+		case "Available Regions":
+			u.AvailRegions = v
+		case "Type":
+			u.Type = v
+		}
+	}
+	return p.error()
+}
+
+// BenchmarkUnMarshallSwitch splits the line and parses it with unMarshallSwitch, as every update was parsed before the
+// column table.
+func BenchmarkUnMarshallSwitch(b *testing.B) {
+	fields := benchFields()
+	line := []byte(benchUpdate)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		u := &UpdSummaryMsg{}
+		if err := unMarshallSwitch(u, strings.Split(string(line), ","), fields, time.UTC); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUnMarshall splits the line and parses the items with the columns built for the fields on every call.
+func BenchmarkUnMarshall(b *testing.B) {
+	fields := benchFields()
+	line := []byte(benchUpdate)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		u := &UpdSummaryMsg{}
+		if err := u.UnMarshall(strings.Split(string(line), ","), fields, time.UTC); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUnMarshallLine parses the line in place with the columns built for the fieldset, as IQC does.
+func BenchmarkUnMarshallLine(b *testing.B) {
+	columns := newUpdColumns(benchFields())
+	line := []byte(benchUpdate)
+	var strs stringCache
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		u := &UpdSummaryMsg{}
+		if err := u.unMarshallLine(line, columns, time.UTC, &strs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUnMarshallLinePooled is BenchmarkUnMarshallLine with IQC.PoolUpdates and IQC.DecimalPrices set.
+func BenchmarkUnMarshallLinePooled(b *testing.B) {
+	columns := newUpdColumns(benchFields())
+	line := []byte(benchUpdate)
+	var strs stringCache
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		u := getUpdSummaryMsg()
+		u.DecimalPrices = true
+		if err := u.unMarshallLine(line, columns, time.UTC, &strs); err != nil {
+			b.Fatal(err)
+		}
+		u.Release()
+	}
+}
@@ -0,0 +1,299 @@
+package iqfeed
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// UpdField identifies a Level 1 update field by its wire name, see:
+// http://www.iqfeed.net/dev/api/docs/Level1UpdateSummaryMessage.cfm.
+type UpdField uint8
+
+// The update fields, the legacy 4.x fieldset comes first followed by the fields added by the 5.x and 6.x protocols.
+const (
+	FieldSymbol UpdField = iota
+	FieldExchangeID
+	FieldLast
+	FieldChange
+	FieldPercentChange
+	FieldTotalVolume
+	FieldIncrementalVolume
+	FieldHigh
+	FieldLow
+	FieldBid
+	FieldAsk
+	FieldBidSize
+	FieldAskSize
+	FieldTick
+	FieldBidTick
+	FieldRange
+	FieldLastTradeTime
+	FieldOpenInterest
+	FieldOpen
+	FieldClose
+	FieldSpread
+	FieldStrike
+	FieldSettle
+	FieldDelay
+	FieldMarketCenter
+	FieldRestrictedCode
+	FieldNetAssetValue
+	FieldAverageMaturity
+	FieldSevenDayYield
+	FieldLastTradeDate
+	FieldReserved
+	FieldExtendedTradingLast
+	FieldExpirationDate
+	FieldRegionalVolume
+	FieldNetAssetValue2
+	FieldExtendedTradingChange
+	FieldExtendedTradingDifference
+	FieldPriceEarningsRatio
+	FieldPercentOffAverageVolume
+	FieldBidChange
+	FieldAskChange
+	FieldChangeFromOpen
+	FieldMarketOpen
+	FieldVolatility
+	FieldMarketCapitalization
+	FieldFractionDisplayCode
+	FieldDecimalPrecision
+	FieldDaysToExpiration
+	FieldPreviousDayVolume
+	FieldRegions
+	FieldOpenRange1
+	FieldCloseRange1
+	FieldOpenRange2
+	FieldCloseRange2
+	FieldNumberOfTradesToday
+	FieldBidTime
+	FieldAskTime
+	FieldVWAP
+	FieldTickID
+	FieldFinancialStatusIndicator
+	FieldSettlementDate
+	FieldTradeMarketCenter
+	FieldBidMarketCenter
+	FieldAskMarketCenter
+	FieldTradeTime
+	FieldAvailableRegions
+	FieldType
+	FieldExtendedTrade
+	FieldExtendedTradeDate
+	FieldExtendedTradeMarketCenter
+	FieldExtendedTradeSize
+	FieldExtendedTradeTime
+	FieldLastDate
+	FieldLastMarketCenter
+	FieldLastSize
+	FieldLastTime
+	FieldMessageContents
+	FieldMostRecentTrade
+	FieldMostRecentTradeConditions
+	FieldMostRecentTradeDate
+	FieldMostRecentTradeMarketCenter
+	FieldMostRecentTradeSize
+	FieldMostRecentTradeTime
+	FieldMostRecentTradeAggressor
+	FieldMostRecentTradeDayCode
+	numUpdFields
+)
+
+// updFieldNames holds the wire name of every UpdField.
+var updFieldNames = [numUpdFields]string{
+	"Symbol", "Exchange ID", "Last", "Change", "Percent Change", "Total Volume", "Incremental Volume", "High", "Low",
+	"Bid", "Ask", "Bid Size", "Ask Size", "Tick", "Bid Tick", "Range", "Last Trade Time", "Open Interest", "Open",
+	"Close", "Spread", "Strike", "Settle", "Delay", "Market Center", "Restricted Code", "Net Asset Value",
+	"Average Maturity", "7 Day Yield", "Last Trade Date", "(Reserved)", "Extended Trading Last", "Expiration Date",
+	"Regional Volume", "Net Asset Value 2", "Extended Trading Change", "Extended Trading Difference",
+	"Price-Earnings Ratio", "Percent Off Average Volume", "Bid Change", "Ask Change", "Change From Open", "Market Open",
+	"Volatility", "Market Capitalization", "Fraction Display Code", "Decimal Precision", "Days to Expiration",
+	"Previous Day Volume", "Regions", "Open Range 1", "Close Range 1", "Open Range 2", "Close Range 2",
+	"Number of Trades Today", "Bid Time", "Ask Time", "VWAP", "TickID", "Financial Status Indicator", "Settlement Date",
+	"Trade Market Center", "Bid Market Center", "Ask Market Center", "Trade Time", "Available Regions", "Type",
+	"Extended Trade", "Extended Trade Date", "Extended Trade Market Center", "Extended Trade Size", "Extended Trade Time",
+	"Last Date", "Last Market Center", "Last Size", "Last Time", "Message Contents", "Most Recent Trade",
+	"Most Recent Trade Conditions", "Most Recent Trade Date", "Most Recent Trade Market Center", "Most Recent Trade Size",
+	"Most Recent Trade Time", "Most Recent Trade Aggressor", "Most Recent Trade Day Code",
+}
+
+// updFieldsByName maps wire names back to their UpdField.
+var updFieldsByName = func() map[string]UpdField {
+	m := make(map[string]UpdField, numUpdFields)
+	for f, name := range updFieldNames {
+		m[name] = UpdField(f)
+	}
+	return m
+}()
+
+// String returns the wire name of the field.
+func (f UpdField) String() string {
+	if f >= numUpdFields {
+		return "UpdField(" + strconv.Itoa(int(f)) + ")"
+	}
+	return updFieldNames[f]
+}
+
+// LookupUpdField returns the field with the wire name, names sent by protocols before 6.0 must be mapped first (see
+// ProtocolVersion).
+func LookupUpdField(name string) (UpdField, bool) {
+	f, ok := updFieldsByName[name]
+	return f, ok
+}
+
+// UpdFields returns every update field in UpdField order.
+func UpdFields() []UpdField {
+	fields := make([]UpdField, numUpdFields)
+	for i := range fields {
+		fields[i] = UpdField(i)
+	}
+	return fields
+}
+
+// SelectFields changes the fieldset of the connection and returns once IQConnect has confirmed it, the summary and
+// update messages received afterwards are parsed with the new fieldset. The fields are first checked against the list
+// IQConnect offers for the protocol (S,REQUEST ALL UPDATE FIELDNAMES), a *FieldError lists the fields it does not offer
+// or did not select. Symbol is always the first field whether it is selected or not.
+func (c *IQC) SelectFields(ctx context.Context, fields ...UpdField) error {
+	if c.ctx == nil {
+		return errNotConnected
+	}
+	all, err := c.request(ctx, SysUpdateFieldNames, "S,REQUEST ALL UPDATE FIELDNAMES")
+	if err != nil {
+		return err
+	}
+	// Fields are sent with the names of the protocol, which may differ from the UpdField names (see updateFieldName).
+	v := c.ProtocolVersion()
+	offered := make(map[UpdField]string, len(all.Fields))
+	for _, name := range all.Fields {
+		if f, ok := LookupUpdField(updateFieldName(name, v)); ok && offered[f] == "" {
+			offered[f] = name
+		}
+	}
+	names := make([]string, len(fields))
+	var missing []UpdField
+	for i, f := range fields {
+		if names[i] = offered[f]; names[i] == "" {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) > 0 {
+		return &FieldError{Fields: missing}
+	}
+	c.state.setUpdateFields(names)
+	cur, err := c.request(ctx, SysCurrentUpdateFieldNames, "S,SELECT UPDATE FIELDS,"+strings.Join(names, ","))
+	if err != nil {
+		return err
+	}
+	selected := make(map[string]bool, len(cur.Fields))
+	for _, name := range cur.Fields {
+		selected[name] = true
+	}
+	for i, f := range fields {
+		if !selected[names[i]] {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) > 0 {
+		return &FieldError{Fields: missing}
+	}
+	return nil
+}
+
+// FieldSet is a set of update fields, UpdSummaryMsg.Present uses it to tell a blank field apart from a zero value.
+type FieldSet [2]uint64
+
+// Add adds f to the set.
+func (s *FieldSet) Add(f UpdField) {
+	s[f/64] |= 1 << (f % 64)
+}
+
+// Has reports whether f is in the set.
+func (s FieldSet) Has(f UpdField) bool {
+	return f < numUpdFields && s[f/64]&(1<<(f%64)) != 0
+}
+
+// Fields returns the fields in the set in UpdField order.
+func (s FieldSet) Fields() []UpdField {
+	var fields []UpdField
+	for f := UpdField(0); f < numUpdFields; f++ {
+		if s.Has(f) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// FundamentalField identifies a field of the fundamental message by its position on the wire.
+type FundamentalField uint8
+
+// The fundamental fields in wire order, see: http://www.iqfeed.net/dev/api/docs/Level1FundamentalMessage.cfm.
+const (
+	FndSymbol FundamentalField = iota
+	FndExchangeID
+	FndPE
+	FndAvgVolume
+	FndFifty2WkHigh
+	FndFifty2WkLow
+	FndCalYearHigh
+	FndCalYearLow
+	FndDivYield
+	FndDivAmt
+	FndDivRate
+	FndPayDate
+	FndExDivDate
+	FndReserved1
+	FndReserved2
+	FndReserved3
+	FndShortInterest
+	FndReserved4
+	FndCurrentYrEPS
+	FndNextYrEPS
+	FndFiveYrGrowthPct
+	FndFiscalYrEnd
+	FndReserved5
+	FndCompanyName
+	FndRootOptionSymbol
+	FndPctHeldByInst
+	FndBeta
+	FndLeaps
+	FndCurrentAssets
+	FndCurrentLiabilities
+	FndBalSheetDate
+	FndLongTermDebt
+	FndComShrOutstanding
+	FndReserved6
+	FndSplitFactor1
+	FndSplitFactor2
+	FndReserved7
+	FndReserved8
+	FndFormatCode
+	FndPrecision
+	FndSIC
+	FndHistVolatility
+	FndSecurityType
+	FndListedMarket
+	FndFifty2WkHighDate
+	FndFifty2WkLowDate
+	FndCalYearHighDate
+	FndCalYearLowDate
+	FndYrEndClose
+	FndMaturityDate
+	FndCouponRate
+	FndExpirationDate
+	FndStrikePrice
+	FndNAICS
+	FndExchangeRoot
+	FndOptionPremMult
+	FndOptionMultDeliv
+	FndSessionOpenTime
+	FndSessionCloseTime
+	FndBaseCurrency
+	FndContractSize
+	FndContractMonths
+	FndMinTickSize
+	FndFirstDeliveryDate
+	FndFIGI
+	FndSecuritySubType
+)
@@ -1,81 +1,77 @@
 package iqfeed
 
 import (
-	"fmt"
-	"math"
-	"os"
+	"log"
 	"strings"
 	"time"
 )
 
 // Write performs a write on the channel data which will be picked up by the writer concurrently and written to iqfeed.
 func (c *IQC) Write(data string) {
-	c.Conn.Write([]byte(data))
+	c.write(data)
 }
 
-// WriteBackup does as the name suggests and write the []byte data directly to a file for re-use later.
-func (c *IQC) writeBackup(d []byte) {
-	if !c.CreateBackup {
-		return
-	}
-	if _, err := os.Stat(c.BackupFile); os.IsNotExist(err) {
-		os.Create(c.BackupFile)
+// write sends the data to iqfeed and returns any error that occurred on the connection.
+func (c *IQC) write(data string) error {
+	conn := c.conn()
+	if conn == nil {
+		// Replayed sessions have no connection to write to.
+		return errNotConnected
 	}
-	f, err := os.OpenFile(c.BackupFile, os.O_APPEND|os.O_RDWR, 0644)
-	if err != nil {
-		fmt.Printf("Could not open file for writing: %s\n", err.Error())
-		return
+	_, err := conn.Write([]byte(data))
+	return err
+}
+
+// WriteBackup does as the name suggests and records the raw line with its receive time for re-use later, see CaptureWriter.
+// When only CreateBackup is set the records are appended to BackupFile without rotation or compression.
+func (c *IQC) writeBackup(d []byte) {
+	if c.Capture == nil {
+		if !c.CreateBackup {
+			return
+		}
+		c.Capture = &CaptureWriter{Path: c.BackupFile, Loc: c.TimeLoc}
 	}
-	defer f.Close()
-	_, err = f.Write(d)
-	if err != nil {
-		fmt.Printf("Could not write data to file: %s\n", err.Error())
-		return
+	if err := c.Capture.WriteLine(time.Now(), d); err != nil {
+		log.Printf("Could not write data to file: %s", err)
 	}
 }
 
 // SetProtocol Changes the current connection's protocol (ex: 5.2).
 func (c *IQC) SetProtocol(protocol string) {
+	c.state.setProtocol(protocol)
 	c.Write("S,SET PROTOCOL," + protocol + "\r\n")
 }
 
 // SetClientName does as the name implies and sets the client message which will also be available in stats.
 func (c *IQC) SetClientName(name string) {
+	c.state.setClientName(name)
 	c.Write("S,SET CLIENT NAME," + name + "\r\n")
 }
 
 // WatchSymbol will issue a command to start watching a symbol, this will return a fundamental and update message with the quotes.
 func (c *IQC) WatchSymbol(symbol string) {
+	c.state.watch(symbol, 'w')
 	c.Write("w" + symbol + "\r\n")
 }
 
-// WatchOptionSymbol tracks a new symbol based on contract date (for option chains), contractDate indicates the date for the option contract and isCall indicates whether it is a call / put contract.
+// WatchOptionSymbol tracks a new symbol based on contract date (for option chains), contractDate indicates the expiration date
+// for the option contract and isCall indicates whether it is a call / put contract. The watched symbol is returned, see OptionSymbol.
 func (c *IQC) WatchOptionSymbol(symbol string, value float64, contractDate time.Time, isCall bool) string {
-	// Final format should be something like: MSFT1220J30.5
-	ydm := contractDate.Format("0602")
-	_, rem := math.Modf(value)
-	pv := fmt.Sprintf("%.0f", value)
-	if rem > 0 {
-		pv = fmt.Sprintf("%.1f", value)
-	}
-
-	if isCall {
-		ydm = ydm + c.getCallChar(contractDate)
-	} else {
-		ydm = ydm + c.getPutChar(contractDate)
-	}
-	tSym := fmt.Sprintf("%s%s%s", symbol, ydm, pv)
+	// Final format should be something like: AAPL2118L150
+	tSym := OptionSymbol(symbol, contractDate, value, isCall)
 	c.WatchSymbol(tSym)
 	return tSym
 }
 
 // TradeOnlyWatch Begins a trades only watch on a symbol for Level 1 updates.
 func (c *IQC) TradeOnlyWatch(symbol string) {
+	c.state.watch(symbol, 't')
 	c.Write("t" + symbol + "\r\n")
 }
 
 // UnwatchSymbol Terminates Level 1 updates for the symbol specified.
 func (c *IQC) UnwatchSymbol(symbol string) {
+	c.state.unwatch(symbol)
 	c.Write("r" + symbol + "\r\n")
 }
 
@@ -101,21 +97,25 @@ func (c *IQC) EnableTSUpdates() {
 
 // RegionWatch Begins watching a symbol for Level 1 Regional updates.
 func (c *IQC) RegionWatch(symbol string) {
+	c.state.setRegion(symbol, true)
 	c.Write("S,REGON," + symbol + "\r\n")
 }
 
 // RegionWatchOff Stops watching a symbol for Level 1 Regional updates.
 func (c *IQC) RegionWatchOff(symbol string) {
+	c.state.setRegion(symbol, false)
 	c.Write("S,REGOFF," + symbol + "\r\n")
 }
 
 // NewsOn Turns on streaming news headlines.
 func (c *IQC) NewsOn() {
+	c.state.setNews(true)
 	c.Write("S,NEWSON\r\n")
 }
 
 // NewsOff Turns off streaming news headlines.
 func (c *IQC) NewsOff() {
+	c.state.setNews(false)
 	c.Write("S,NEWSOFF\r\n")
 }
 
@@ -141,11 +141,16 @@ func (c *IQC) ReqCurrentUpdateFNames() {
 }
 
 // SelectUpdateFields Change your fieldset for this connection. This fieldset applies to all summary and update messages you receive on this connection. (Comma seperated list of field names).
+//
+// Deprecated: the names are sent unchecked, use SelectFields which validates the fields and waits for them to be selected.
 func (c *IQC) SelectUpdateFields(fields ...string) {
+	c.state.setUpdateFields(fields)
 	c.Write("S,SELECT UPDATE FIELDS," + strings.Join(fields, ",") + "\r\n")
 }
 
 // RequestListedMarkets will request a list of all the listed markets from the feed.
+//
+// Deprecated: the listed markets are only served on the lookup port, use LookupClient.ListedMarkets or LookupClient.MarketTables.
 func (c *IQC) RequestListedMarkets() {
 	c.Write("SLM\r\n")
 }
@@ -162,6 +167,7 @@ func (c *IQC) RequestWatches() {
 
 // UnwatchAllSymbols Unwatch all currently watched symbols.
 func (c *IQC) UnwatchAllSymbols() {
+	c.state.unwatchAll()
 	c.Write("S,UNWATCH ALL\r\n")
 }
 